@@ -6,8 +6,8 @@ type api struct {
 	vm *VM
 }
 
-func (*api) Trace(message ...interface{}) {
-	alien_trace(message...)
+func (p *api) Trace(message ...interface{}) {
+	alien_trace(p.vm, message...)
 }
 
 func (p *api) Halt(info *List) {
@@ -15,18 +15,47 @@ func (p *api) Halt(info *List) {
 }
 
 func (p *api) Recv() Word {
-	return p.vm.io.Recv()
+	return p.RecvFrom(p.vm.io)
 }
 
 func (p *api) Send(w Word) {
-	p.vm.io.Send(w)
+	p.SendTo(p.vm.io, w)
+}
+
+//Receive from port, abandoning the receive with a runtime error if the
+//context of the VM is done first. Ports that are not ContextPorts block
+//regardless.
+func (p *api) RecvFrom(port Port) Word {
+	cp, ok := port.(ContextPort)
+	if !ok || p.vm.done == nil {
+		return port.Recv()
+	}
+	w, err := cp.RecvContext(p.vm.ctx)
+	if err != nil {
+		cancelled(p.vm, err)
+	}
+	return w
+}
+
+//Send w to port, abandoning the send with a runtime error if the
+//context of the VM is done first. Ports that are not ContextPorts block
+//regardless.
+func (p *api) SendTo(port Port, w Word) {
+	cp, ok := port.(ContextPort)
+	if !ok || p.vm.done == nil {
+		port.Send(w)
+		return
+	}
+	if err := cp.SendContext(p.vm.ctx, w); err != nil {
+		cancelled(p.vm, err)
+	}
 }
 
 //If string, attempt to convert
 func (p *api) NumberOrElse(w Word) *Number {
 	n, ok := w.(*Number)
 	if !ok {
-		n, ok = NewNumberFromBytes(w.Ser().Bytes())
+		n, ok = NewNumberFrom(w)
 		if !ok {
 			TypeMismatch(p.vm, "number", w.Type())
 		}
@@ -34,6 +63,15 @@ func (p *api) NumberOrElse(w Word) *Number {
 	return n
 }
 
+//If string, attempt to convert
+func (p *api) DecimalOrElse(w Word) *Decimal {
+	d, ok := NewDecimalFrom(w)
+	if !ok {
+		TypeMismatch(p.vm, "decimal", w.Type())
+	}
+	return d
+}
+
 func (p *api) QuoteOrElse(w Word) Quote {
 	q, ok := w.(Quote)
 	if !ok {
@@ -104,6 +142,14 @@ func (p *api) AlienOrElse(w Word) Alien {
 	return a
 }
 
+func (p *api) ErrorOrElse(w Word) Error {
+	e, ok := w.(Error)
+	if !ok {
+		TypeMismatch(p.vm, "error", w.Type())
+	}
+	return e
+}
+
 /*
  *      Returns the bytes of a symbol or quote.
  * We do not check that the quote is in fact literal as there are many possible
@@ -155,21 +201,32 @@ func (p *api) IsInvokable(w Word) (Word, bool) {
 	return nil, false
 }
 
+//A quote invoked by any of the Invoke family other than InvokeLoopBody and
+//InvokeCatchControl stops a return, as if it were invoked as a command
 func (p *api) InvokeOrElse(args *List) (ret Word) {
+	return p._invoke(args, true)
+}
+
+//call is false for the blocks of control commands, which a return passes
+//through unless they were invoked by name
+func (p *api) _invoke(args *List, call bool) (ret Word) {
 	//simulate the Noop, {}
 	if args == nil {
 		return Null
 	}
-	w, c, args := p.vm.peval(args, uint(args.Len()-1))
+	p.vm._push_frame(args)
+	w, c, cargs, named := p.vm.peval(args, uint(args.Len()-1))
 	if _, is_defer := w.(*defert); is_defer {
 		RuntimeError(p.vm, "Cannot register a defer via Invoke*")
 		return
 	}
 	if c != nil {
-		ret = p.vm.eval(c, args)
+		ret = p.vm.eval(c, cargs, call || named)
 	} else {
 		ret = w
 	}
+	p.vm._hook_after(args, ret)
+	p.vm._pop_frame()
 	return
 }
 
@@ -177,6 +234,7 @@ func (p *api) Invoke(args *List) (ret Word, err Error) {
 	if args == nil {
 		return Null, nil
 	}
+	depth := p.vm._depth()
 	defer func() {
 		if x := recover(); x != nil {
 			switch t := x.(type) {
@@ -184,15 +242,144 @@ func (p *api) Invoke(args *List) (ret Word, err Error) {
 				//note that we are implicitly allowing halt_control_code
 				//to bubble
 				panic(x)
+			case Error:
+				ret, err = nil, t
+				p.vm._unwind(depth)
+			}
+		}
+	}()
+	ret = p.InvokeOrElse(args)
+	return
+}
+
+//Like Invoke, but a halt is stopped as well as an error, returning halted
+//true with the arguments given to halt as ret, so the caller can clean up
+//before passing the halt on with Halt. Kills are not stopped.
+func (p *api) InvokeCatchHalt(args *List) (ret Word, err Error, halted bool) {
+	if args == nil {
+		return Null, nil, false
+	}
+	depth := p.vm._depth()
+	defer func() {
+		if x := recover(); x != nil {
+			switch t := x.(type) {
+			default:
+				panic(x)
+			case halt_control_code:
+				ret, halted = (*List)(t), true
 			case Error:
 				ret, err = nil, t
 			}
+			p.vm._unwind(depth)
 		}
 	}()
 	ret = p.InvokeOrElse(args)
 	return
 }
 
+//A transfer of control stopped by InvokeCatchControl, such as a halt or a
+//break out of a loop further out, for the caller to pass on with Resume
+type Control struct {
+	code interface{}
+}
+
+//Like InvokeCatchHalt, but a halt and anything else that transfers control
+//past the caller, such as a break, continue or return, are returned as c so
+//the caller can clean up before passing them on with Resume. Kills are not
+//stopped. A return in args passes through to the quote invoking the caller.
+func (p *api) InvokeCatchControl(args *List) (ret Word, err Error, c *Control) {
+	if args == nil {
+		return Null, nil, nil
+	}
+	depth := p.vm._depth()
+	defer func() {
+		if x := recover(); x != nil {
+			switch t := x.(type) {
+			default:
+				panic(x)
+			case halt_control_code:
+				ret, c = (*List)(t), &Control{x}
+			case break_control_code, continue_control_code,
+				return_control_code:
+				ret, c = Null, &Control{x}
+			case Error:
+				ret, err = nil, t
+			}
+			p.vm._unwind(depth)
+		}
+	}()
+	ret = p._invoke(args, false)
+	return
+}
+
+//Pass on a transfer of control stopped by InvokeCatchControl
+func (p *api) Resume(c *Control) {
+	panic(c.code)
+}
+
+//Invoke the body of a loop. A break in the body stops it, returning the value
+//given to break with broke true, and a continue stops it, returning Null.
+//Break and continue are errors unless a loop body is being invoked by the
+//quote they are in, and stop at a quote invoked as a command just as return
+//does. A return in the body passes through to the quote invoking the loop.
+func (p *api) InvokeLoopBody(args *List) (ret Word, broke bool) {
+	depth := p.vm._depth()
+	p.vm.loops++
+	defer func() {
+		p.vm.loops--
+		if x := recover(); x != nil {
+			switch t := x.(type) {
+			default:
+				panic(x)
+			case break_control_code:
+				ret, broke = t.value, true
+			case continue_control_code:
+				ret = Null
+			}
+			p.vm._unwind(depth)
+		}
+	}()
+	ret = p._invoke(args, false)
+	return
+}
+
+//Stop the innermost loop body being invoked and the loop with it, which
+//returns value
+func (p *api) Break(value Word) {
+	if p.vm.loops == 0 {
+		RuntimeError(p.vm, "break outside of a loop")
+	}
+	panic(break_control_code{value})
+}
+
+//Stop the innermost loop body being invoked and go on with the loop
+func (p *api) Continue() {
+	if p.vm.loops == 0 {
+		RuntimeError(p.vm, "continue outside of a loop")
+	}
+	panic(continue_control_code{})
+}
+
+//Stop the quote being evaluated, which returns value. A return passes through
+//the quotes an Alien invokes in its own place, such as the branches of if, and
+//the bodies of loops and try, to the innermost quote invoked as a command.
+func (p *api) Return(value Word) {
+	panic(return_control_code{value})
+}
+
+//Parse the source read from in and evaluate it in the current namespace, as
+//if it were a quote invoked with args. name is used to report positions, as
+//in (*VM).ParseProgram. Unlike (*VM).Run, this is meant to be called while the
+//VM is running, such as from an Alien or a Hook. Errors, including syntax
+//errors in the source, are returned.
+func (p *api) EvalSource(name string, in reader, args *List) (Word, Error) {
+	q, err := _parse_source(name, in)
+	if err != nil {
+		return nil, err
+	}
+	return p.Invoke(&List{q, args})
+}
+
 //The TailInvoke* family is only to be called when the result is to be
 //returned from the callee.
 func (*api) TailInvoke(args *List) Word {
@@ -243,30 +430,7 @@ func (p *api) PartialEval(q Quote) (*List, bool) {
 	var ghead, gtail *List
 	for c := cmds; c != nil; c = c.next {
 		var head, tail *List
-		for s := c.cmd; s != nil; s = s.next {
-			var w Word
-			switch s.tag {
-			case synLiteral, synQuote:
-				w = s.val.(Word)
-			default:
-				//this is ugly but no clean way to extract a rewrite1
-				//out of rewrite without increasing the complexity or
-				//compromising its performance in the face of a splice.
-				//fortunately this is the only place we call it where
-				//it wasn't meant to be. Since we handle quote separately,
-				//we don't need to worry about it getting unprotected.
-				l, _ := p.vm.rewrite(&sNode{s.tag, s.val, nil})
-				if s.tag == synSplice {
-					if l == nil {
-						continue //so we don't write nil to the list
-					}
-					for ; l.Next != nil; l = l.Next {
-					}
-					w = l.Next
-				} else {
-					w = l.Value
-				}
-			}
+		fill := func(w Word) {
 			if head != nil {
 				tail.Next = &List{w, nil}
 				tail = tail.Next
@@ -275,6 +439,25 @@ func (p *api) PartialEval(q Quote) (*List, bool) {
 				tail = head
 			}
 		}
+		for s := c.cmd; s != nil; s = s.next {
+			switch s.tag {
+			case synLiteral, synQuote:
+				fill(s.val.(Word))
+			default:
+				//compile and run just this word. Since we handle quote
+				//separately, we don't need to worry about it getting
+				//unprotected. A splice may leave any number of words.
+				code := &_code{}
+				code._word(s)
+				sp := len(p.vm.stack)
+				p.vm._build(code.instrs, 0)
+				for i, w := range p.vm.stack[sp:] {
+					fill(w)
+					p.vm.stack[sp+i] = nil
+				}
+				p.vm.stack = p.vm.stack[:sp]
+			}
+		}
 		if ghead != nil {
 			gtail.Next = &List{head, nil}
 			gtail = gtail.Next
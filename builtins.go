@@ -6,6 +6,7 @@ func BI_eval(vm *VM, args *List, ac uint) (ret Word) {
 	if ac == 0 {
 		ArgumentError(vm, "eval", "code argument*", args)
 	}
+	depth := vm._depth()
 	defer func() {
 		if x := recover(); x != nil {
 			switch t := x.(type) {
@@ -16,6 +17,7 @@ func BI_eval(vm *VM, args *List, ac uint) (ret Word) {
 			case Error:
 				ret = t
 			}
+			vm._unwind(depth)
 		}
 	}()
 	//so we can't mess up the parent's namespace
@@ -41,13 +43,43 @@ func _spawn(vm *VM, args *List) (*VM, *List) {
 	return spawned, tail
 }
 
+//if the spawn fails, the child acquired for it is given back
+func _spawn_or_release(vm *VM, args *List, q *_quotas) (*VM, *List) {
+	defer func() {
+		if x := recover(); x != nil {
+			q._release_child()
+			panic(x)
+		}
+	}()
+	return _spawn(vm, args)
+}
+
+func _safe_eval_arg_err(vm *VM, args *List) {
+	ArgumentError(vm, "safe-eval", "[--steps n]? code argument*", args)
+}
+
 func BI_safe_eval(vm *VM, args *List, ac uint) Word {
 	if ac == 0 {
-		ArgumentError(vm, "safe-eval", "code argument*", args)
+		_safe_eval_arg_err(vm, args)
+	}
+	var steps int64
+	if StrEqualsSym("--steps", args.Value.Ser()) {
+		if ac < 3 {
+			_safe_eval_arg_err(vm, args)
+		}
+		n, ok := vm.API.NumberOrElse(args.Next.Value).Int()
+		if !ok || n <= 0 {
+			TypeMismatch(vm, "positive integer", args.Next.Value.Type())
+		}
+		steps = n
+		args = args.Next.Next
 	}
 	//TODO: add a "--with-env dict"
 	spawned, rargs := _spawn(vm, args)
 	defer spawned.Destroy()
+	if steps != 0 {
+		spawned.SetStepLimit(steps)
+	}
 	ret, err := spawned.Exec(rargs)
 	if err != nil {
 		return err
@@ -73,16 +105,24 @@ func BI_go(vm *VM, args *List, ac uint) Word {
 		args = args.Next.Next
 	}
 
-	spawned, rargs := _spawn(vm, args)
+	vm._acquire_child()
+	quotas := vm.quotas
+	spawned, rargs := _spawn_or_release(vm, args, quotas)
 	if port != nil {
 		spawned.Redirect(port)
 	}
 
 	go func() {
+		defer quotas._release_child()
 		defer spawned.Destroy()
 		vm.API.Trace("goroutine spawned")
 		if _, err := spawned.Exec(rargs); err != nil {
-			spawned.io.Send(err)
+			//if we were cancelled, there may be no one left to listen
+			if cp, ok := spawned.io.(ContextPort); ok {
+				cp.SendContext(spawned.Context(), err)
+			} else {
+				spawned.io.Send(err)
+			}
 		}
 	}()
 
@@ -97,4 +137,5 @@ var Core = map[string]interface{}{
 	"eval":      BI_eval,
 	"safe-eval": BI_safe_eval,
 	"go":        BI_go,
+	"import":    BI_import,
 }
@@ -0,0 +1,253 @@
+package gelo
+
+//Quotes are compiled from their parse tree to a flat array of instructions
+//the first time they are invoked. Each command becomes a mark, the
+//instructions that build its words on the VM's stack and one instruction that
+//invokes them. A clause is compiled in place, so its result is simply left on
+//the stack for the command it is a part of.
+
+type _opcode byte
+
+const (
+	op_mark      _opcode = iota //start a command, remember the stack height
+	op_push                     //push w
+	op_deref                    //push the value of the variable named w
+	op_deref_top                //replace the top word with the value it names
+	op_splice                   //replace the list on top with its items
+	op_clause                   //invoke the marked command and push its result
+	op_call                     //invoke the marked command, discard its result
+	op_defer                    //like op_call, for commands headed by defer
+	op_tail                     //invoke the marked command in place of ours
+)
+
+type _instr struct {
+	op  _opcode
+	w   Word   //operand of op_push and op_deref
+	src *sNode //the command as parsed, for op_mark and the invoking ops
+}
+
+type _code struct {
+	instrs []_instr
+}
+
+//saved by op_mark and restored by the invoking instructions
+type _mark struct {
+	sp  int
+	pos SrcPos
+}
+
+//the height of the VM's stacks, so they can be restored after a panic
+type _depth struct {
+	frames, stack, marks int
+}
+
+var defer_sym = interns("defer")
+
+func compile(script *command) *_code {
+	c := &_code{}
+	for ; script != nil; script = script.next {
+		op := op_call
+		if script.next == nil {
+			op = op_tail
+		} else if script.cmd.tag == synLiteral &&
+			script.cmd.val.(Word).Equals(defer_sym) {
+			op = op_defer
+		}
+		c._line(script.cmd, op)
+	}
+	return c
+}
+
+func (c *_code) _emit(op _opcode, w Word) {
+	c.instrs = append(c.instrs, _instr{op: op, w: w})
+}
+
+func (c *_code) _line(line *sNode, op _opcode) {
+	c.instrs = append(c.instrs, _instr{op: op_mark, src: line})
+	for n := line; n != nil; n = n.next {
+		c._word(n)
+	}
+	c.instrs = append(c.instrs, _instr{op: op, src: line})
+}
+
+func (c *_code) _word(n *sNode) {
+	switch n.tag {
+	case synLiteral, synQuote:
+		c._emit(op_push, n.val.(Word))
+	case synIndirect:
+		c._deref(n.val.(*sNode))
+	case synClause:
+		line := n.val.(*sNode)
+		c._line(line, op_clause)
+	case synSplice:
+		item := n.val.(*sNode)
+		if item.tag == synClause {
+			//the result of a clause is spliced as is, not dereferenced,
+			//because we expect a list not a name
+			line := item.val.(*sNode)
+			c._line(line, op_clause)
+		} else {
+			c._deref(item)
+		}
+		c._emit(op_splice, nil)
+	default:
+		systemError(nil, "invalid node type compiled--parser incorrect", n)
+	}
+}
+
+func (c *_code) _deref(item *sNode) {
+	switch item.tag {
+	case synClause:
+		line := item.val.(*sNode)
+		c._line(line, op_clause)
+		c._emit(op_deref_top, nil)
+	case synQuote:
+		c._emit(op_deref, item.val.(Quote).Ser())
+	case synLiteral:
+		c._emit(op_deref, item.val.(Word))
+	default:
+		systemError(nil, "invalid node type dereferenced--parser incorrect",
+			item)
+	}
+}
+
+func (q *quote) fbytecode() (code *_code, ok bool) {
+	if q.compiled != nil {
+		return q.compiled, true
+	}
+	cmds, ok := q.fcode()
+	if !ok || cmds == nil {
+		return nil, ok
+	}
+	q.compiled = compile(cmds)
+	return q.compiled, true
+}
+
+func (vm *VM) _push(w Word) {
+	vm.stack = append(vm.stack, w)
+}
+
+//Runs the instructions that build words from pc on and returns the pc of the
+//first invoking instruction that is not a clause.
+func (vm *VM) _build(instrs []_instr, pc int) int {
+	for ; pc < len(instrs); pc++ {
+		in := &instrs[pc]
+		switch in.op {
+		default:
+			return pc
+		case op_mark:
+			vm.marks = append(vm.marks, _mark{len(vm.stack), vm.pos})
+			if in.src != nil && in.src.pos.Known() {
+				vm.pos = in.src.pos
+			}
+		case op_push:
+			vm._push(in.w)
+		case op_deref:
+			w := vm.Ns.LookupOrElse(in.w)
+			run_trace(vm, "derefed", in.w, "=>", w)
+			vm._push(w)
+		case op_deref_top:
+			top := len(vm.stack) - 1
+			var name Word
+			switch t := vm.stack[top].(type) {
+			default:
+				TypeMismatch(vm, "symbol or quote", t.Type())
+			case Quote:
+				name = t.Ser()
+			case Symbol:
+				name = t
+			}
+			vm.stack[top] = vm.Ns.LookupOrElse(name)
+			run_trace(vm, "derefed", name, "=>", vm.stack[top])
+		case op_splice:
+			top := len(vm.stack) - 1
+			s, ok := vm.stack[top].(*List)
+			if !ok {
+				RuntimeError(vm, "Attempted to splice nonlist")
+			}
+			vm.stack[top] = nil
+			vm.stack = vm.stack[:top]
+			for ; s != nil; s = s.Next {
+				vm._push(s.Value)
+			}
+		case op_clause:
+			line, ac, pos := vm._collect(in)
+			vm._push(vm._invoke_clause(line, ac))
+			vm.pos = pos
+		}
+	}
+	return pc
+}
+
+//pops the words since the last mark into a rewritten command
+func (vm *VM) _collect(in *_instr) (line *List, ac uint, pos SrcPos) {
+	n := len(vm.marks) - 1
+	m := vm.marks[n]
+	vm.marks = vm.marks[:n]
+	words := vm.stack[m.sp:]
+	if len(words) != 0 {
+		//one allocation for the whole command
+		nodes := make([]List, len(words))
+		for i, w := range words {
+			nodes[i].Value = w
+			if i != len(nodes)-1 {
+				nodes[i].Next = &nodes[i+1]
+			}
+			words[i] = nil
+		}
+		line, ac = &nodes[0], uint(len(nodes)-1)
+	}
+	vm.stack = vm.stack[:m.sp]
+	if vm.cover != nil {
+		vm.cover._hit(in.src)
+	}
+	run_trace(vm, "rewrote", in.src, "to", line)
+	return line, ac, m.pos
+}
+
+func (vm *VM) _invoke_clause(line *List, ac uint) Word {
+	vm._push_frame(line)
+	w, c, args, call := vm.peval(line, ac)
+	if _, ok := w.(*defert); ok {
+		RuntimeError(vm, "defer commands must not be in a clause")
+	}
+	if c != nil {
+		w = vm.eval(c, args, call)
+	}
+	vm._hook_after(line, w)
+	vm._pop_frame()
+	return w
+}
+
+//if the head of line names a defer, we can attach it without the full
+//invocation machinery
+func (vm *VM) _is_defer(line *List) bool {
+	if line == nil {
+		return false
+	}
+	w, ok := vm.Ns.Lookup(line.Value)
+	if !ok {
+		return false
+	}
+	_, ok = w.(*defert)
+	return ok
+}
+
+func (vm *VM) _depth() _depth {
+	return _depth{len(vm.frames), len(vm.stack), len(vm.marks)}
+}
+
+//used when recovering from a panic, which leaves whatever was being built
+//on the stacks
+func (vm *VM) _unwind(d _depth) {
+	vm._unwind_frames(d.frames)
+	for i := d.stack; i < len(vm.stack); i++ {
+		vm.stack[i] = nil
+	}
+	if d.stack < len(vm.stack) {
+		vm.stack = vm.stack[:d.stack]
+	}
+	if d.marks < len(vm.marks) {
+		vm.marks = vm.marks[:d.marks]
+	}
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"os"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+//a VM with the core and all of the commands registered and, if prelude is
+//true, the prelude from the examples run
+func _new_vm(t *testing.T, prelude bool) *gelo.VM {
+	t.Helper()
+	vm := gelo.NewVM(gelo.NewChan())
+	vm.RegisterBundle(gelo.Core)
+	vm.RegisterBundles(All)
+	if prelude {
+		f, err := os.Open("../examples/prelude.gel")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := vm.Run("prelude.gel", f, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return vm
+}
+
+//evaluates src in vm and returns the serialization of the result, failing t if
+//there is an error
+func _eval(t *testing.T, vm *gelo.VM, src string) string {
+	t.Helper()
+	ret, err := vm.Do(src)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", src, err)
+	}
+	return ret.Ser().String()
+}
+
+//evaluates src in vm and returns the error it raises, failing t if there is
+//none
+func _eval_err(t *testing.T, vm *gelo.VM, src string) gelo.Error {
+	t.Helper()
+	ret, err := vm.Do(src)
+	if err == nil {
+		t.Fatalf("%s: expected an error, got %s", src, ret.Ser())
+	}
+	return err
+}
+
+type _eval_case struct {
+	src, want string
+}
+
+//evaluates each case in a new VM
+func _eval_cases(t *testing.T, prelude bool, cases []_eval_case) {
+	t.Helper()
+	for _, c := range cases {
+		vm := _new_vm(t, prelude)
+		if got := _eval(t, vm, c.src); got != c.want {
+			t.Errorf("%s = %s, want %s", c.src, got, c.want)
+		}
+	}
+}
@@ -153,7 +153,366 @@ func Case_of(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	return gelo.Null //no match, no otherwise
 }
 
+/*
+ * try body ['catch var ['of kind+]? handler]* ['finally cleanup]?
+ *
+ * Invokes body and returns its result. If body raises an error, the first
+ * catch clause that names the kind of the error, or that names no kinds,
+ * binds the error to var and invokes handler, whose result is returned
+ * instead. An error that no clause catches is raised again. Whatever happened,
+ * cleanup is then invoked, its result ignored, unless it raises an error or
+ * halts, which replaces what happened before.
+ *
+ * A halt, break or continue in body or handler runs cleanup and is passed on,
+ * unless cleanup does one itself, which replaces it. Kills, cancelled
+ * evaluations and exceeded step limits stop the VM from evaluating anything
+ * more, so they are neither caught nor is cleanup invoked.
+ */
+type _try_clause struct {
+	name    gelo.Word
+	kinds   []string
+	handler gelo.Word
+}
+
+const _try_spec = "body ['catch var ['of kind+]? handler]* ['finally cleanup]?"
+
+func _try_parse(vm *gelo.VM, args *gelo.List) (clauses []_try_clause,
+	cleanup gelo.Word) {
+	for rest := args.Next; rest != nil; {
+		switch rest.Value.Ser().String() {
+		case "catch":
+			if rest.Next == nil || rest.Next.Next == nil {
+				gelo.ArgumentError(vm, "try", _try_spec, args)
+			}
+			c := _try_clause{name: rest.Next.Value}
+			rest = rest.Next.Next
+			if rest.Value.Ser().String() == "of" {
+				//the kinds run up to the handler, the last item of the clause
+				rest = rest.Next
+				for ; rest != nil && !_try_clause_end(rest.Next); rest = rest.Next {
+					c.kinds = append(c.kinds, rest.Value.Ser().String())
+				}
+				if rest == nil || len(c.kinds) == 0 {
+					gelo.ArgumentError(vm, "try", _try_spec, args)
+				}
+			}
+			c.handler = rest.Value
+			rest = rest.Next
+			clauses = append(clauses, c)
+		case "finally":
+			if rest.Next == nil || rest.Next.Next != nil {
+				gelo.ArgumentError(vm, "try", _try_spec, args)
+			}
+			return clauses, rest.Next.Value
+		default:
+			gelo.ArgumentError(vm, "try", _try_spec, args)
+		}
+	}
+	return clauses, nil
+}
+
+func _try_clause_end(l *gelo.List) bool {
+	if l == nil {
+		return true
+	}
+	s := l.Value.Ser().String()
+	return s == "catch" || s == "finally"
+}
+
+func (c *_try_clause) catches(err gelo.Error) bool {
+	if len(c.kinds) == 0 {
+		return true
+	}
+	kind := gelo.ErrorKind(err)
+	for _, k := range c.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func _try_invoke(vm *gelo.VM, w gelo.Word) (gelo.Word, gelo.Error,
+	*gelo.Control) {
+	inv, ok := vm.API.IsInvokable(w)
+	if !ok {
+		return w, nil, nil
+	}
+	return vm.API.InvokeCatchControl(gelo.AsList(inv))
+}
+
+func (c *_try_clause) handle(vm *gelo.VM, err gelo.Error) (gelo.Word,
+	gelo.Error, *gelo.Control) {
+	//DepthOf counts the current namespace as 1
+	if d, there := vm.Ns.DepthOf(c.name); there && d == 1 {
+		defer vm.Ns.Set(0, c.name, vm.Ns.LookupOrElse(c.name))
+	} else {
+		defer vm.Ns.Del(c.name)
+	}
+	vm.Ns.Set(0, c.name, err)
+	return _try_invoke(vm, c.handler)
+}
+
+//errors after which the VM cannot evaluate anything
+func _try_fatal(err gelo.Error) bool {
+	switch gelo.ErrorKind(err) {
+	case gelo.ErrKindCancelled, gelo.ErrKindStepLimit:
+		return true
+	}
+	return false
+}
+
+func Try(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac == 0 {
+		gelo.ArgumentError(vm, "try", _try_spec, args)
+	}
+	clauses, cleanup := _try_parse(vm, args)
+	ret, err, ctl := _try_invoke(vm, args.Value)
+	if err != nil && !_try_fatal(err) {
+		for i := range clauses {
+			if clauses[i].catches(err) {
+				ret, err, ctl = clauses[i].handle(vm, err)
+				break
+			}
+		}
+	}
+	if err != nil && _try_fatal(err) {
+		panic(err)
+	}
+	if cleanup != nil {
+		_, cerr, cctl := _try_invoke(vm, cleanup)
+		if cerr != nil {
+			panic(cerr)
+		}
+		if cctl != nil {
+			vm.API.Resume(cctl)
+		}
+	}
+	if ctl != nil {
+		vm.API.Resume(ctl)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+/*
+ * while cond body
+ * repeat n body
+ * for var 'in list body
+ * for var 'from a 'to b ['by step]? body
+ *
+ * while invokes body for as long as cond is true, repeat invokes it n times,
+ * passing it the number of times it was invoked before, and for invokes it
+ * for each item of list, or each number from a to b inclusive counting by
+ * step, passing it the item or number and binding it to var. step is 1, or -1
+ * if b is less than a, by default. var is restored once the loop is done.
+ *
+ * break value? in body stops the loop, which returns value, or Null if none is
+ * given, and continue stops body and goes on with the loop. Otherwise a loop
+ * returns the result of body the last time it was invoked, or Null if it never
+ * was.
+ */
+
+//Invoke body with args as the body of a loop, returning its result and, if it
+//broke out of the loop, true
+func _loop_body(vm *gelo.VM, body gelo.Word, args *gelo.List) (gelo.Word,
+	bool) {
+	return vm.API.InvokeLoopBody(&gelo.List{Value: body, Next: args})
+}
+
+func While(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 2 {
+		gelo.ArgumentError(vm, "while", "cond body", args)
+	}
+	body := vm.API.InvokableOrElse(args.Next.Value)
+	var ret gelo.Word = gelo.Null
+	for _condition(vm, args.Value) {
+		var broke bool
+		if ret, broke = _loop_body(vm, body, nil); broke {
+			return ret
+		}
+	}
+	return ret
+}
+
+func Repeat(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 2 {
+		gelo.ArgumentError(vm, "repeat", "n body", args)
+	}
+	n, ok := vm.API.NumberOrElse(args.Value).Int()
+	if !ok || n < 0 {
+		gelo.TypeMismatch(vm, "non-negative integer", args.Value.Type())
+	}
+	body := vm.API.InvokableOrElse(args.Next.Value)
+	var ret gelo.Word = gelo.Null
+	for i := int64(0); i < n; i++ {
+		count, _ := gelo.NewNumberFromGo(i)
+		var broke bool
+		if ret, broke = _loop_body(vm, body, gelo.NewList(count)); broke {
+			return ret
+		}
+	}
+	return ret
+}
+
+const _for_spec = "var 'in list body | var 'from a 'to b ['by step]? body"
+
+func For(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac < 4 {
+		gelo.ArgumentError(vm, "for", _for_spec, args)
+	}
+	name := args.Value
+	switch args.Next.Value.Ser().String() {
+	case "in":
+		if ac != 4 {
+			gelo.ArgumentError(vm, "for", _for_spec, args)
+		}
+		list := vm.API.ListOrElse(args.Next.Next.Value)
+		body := vm.API.InvokableOrElse(args.Next.Next.Next.Value)
+		defer _for_restore(vm, name)()
+		var ret gelo.Word = gelo.Null
+		for ; list != nil; list = list.Next {
+			vm.Ns.Set(0, name, list.Value)
+			var broke bool
+			ret, broke = _loop_body(vm, body, gelo.NewList(list.Value))
+			if broke {
+				return ret
+			}
+		}
+		return ret
+	case "from":
+		rest := args.Next.Next
+		if (ac != 6 && ac != 8) || rest.Next.Value.Ser().String() != "to" {
+			gelo.ArgumentError(vm, "for", _for_spec, args)
+		}
+		from, to := rest.Value, rest.Next.Next.Value
+		var by gelo.Word
+		rest = rest.Next.Next.Next
+		if ac == 8 {
+			if rest.Value.Ser().String() != "by" {
+				gelo.ArgumentError(vm, "for", _for_spec, args)
+			}
+			by = rest.Next.Value
+			rest = rest.Next.Next
+		}
+		body := vm.API.InvokableOrElse(rest.Value)
+		//counted in decimals if any bound or the step is one, as in math
+		if like := _first_decimal(args); like != nil {
+			return _for_decimal(vm, name, from, to, by, like, body)
+		}
+		a := vm.API.NumberOrElse(from)
+		b := vm.API.NumberOrElse(to)
+		step := _one
+		if c, ok := a.Cmp(b); ok && c > 0 {
+			step = _one.Neg()
+		}
+		if by != nil {
+			step = vm.API.NumberOrElse(by)
+			if step.Sign() == 0 {
+				gelo.RuntimeError(vm, "for step cannot be 0")
+			}
+		}
+		defer _for_restore(vm, name)()
+		dir := step.Sign()
+		var ret gelo.Word = gelo.Null
+		for i := a; ; i = i.Add(step) {
+			if c, ok := i.Cmp(b); !ok || c*dir > 0 {
+				return ret
+			}
+			vm.Ns.Set(0, name, i)
+			var broke bool
+			if ret, broke = _loop_body(vm, body, gelo.NewList(i)); broke {
+				return ret
+			}
+		}
+	}
+	gelo.ArgumentError(vm, "for", _for_spec, args)
+	return nil
+}
+
+//for name from .. to .. by .., as in For, counting in decimals. by is nil if
+//not given.
+func _for_decimal(vm *gelo.VM, name, from, to, by gelo.Word,
+	like *gelo.Decimal, body gelo.Word) gelo.Word {
+	a := _as_decimal(vm, from, like)
+	b := _as_decimal(vm, to, like)
+	step := _decimal_one
+	if a.Cmp(b) > 0 {
+		step = _decimal_one.Neg()
+	}
+	if by != nil {
+		step = _as_decimal(vm, by, like)
+		if step.Sign() == 0 {
+			gelo.RuntimeError(vm, "for step cannot be 0")
+		}
+	}
+	defer _for_restore(vm, name)()
+	dir := step.Sign()
+	var ret gelo.Word = gelo.Null
+	for i := a; i.Cmp(b)*dir <= 0; i = i.Add(step) {
+		vm.Ns.Set(0, name, i)
+		var broke bool
+		if ret, broke = _loop_body(vm, body, gelo.NewList(i)); broke {
+			return ret
+		}
+	}
+	return ret
+}
+
+//returns a function to restore name to what it is now
+func _for_restore(vm *gelo.VM, name gelo.Word) func() {
+	//DepthOf counts the current namespace as 1
+	if d, there := vm.Ns.DepthOf(name); there && d == 1 {
+		old := vm.Ns.LookupOrElse(name)
+		return func() { vm.Ns.Set(0, name, old) }
+	}
+	return func() { vm.Ns.Del(name) }
+}
+
+func Break(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	switch ac {
+	case 0:
+		vm.API.Break(gelo.Null)
+	case 1:
+		vm.API.Break(args.Value)
+	}
+	gelo.ArgumentError(vm, "break", "value?", args)
+	return nil
+}
+
+func Continue(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 0 {
+		gelo.ArgumentError(vm, "continue", "", args)
+	}
+	vm.API.Continue()
+	return nil
+}
+
+//Returns value, or the empty string, from the command being run. A return in
+//the branch of an if or the body of a loop returns from the command that they
+//are in, not just the branch or loop. A return in a defer returns from the
+//command that deferred it, in place of what it was returning.
+func Return(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	switch ac {
+	case 0:
+		vm.API.Return(gelo.Null)
+	case 1:
+		vm.API.Return(args.Value)
+	}
+	gelo.ArgumentError(vm, "return", "value?", args)
+	return nil
+}
+
 var ControlCommands = map[string]interface{}{
-	"if":      If,
-	"case-of": Case_of,
+	"if":       If,
+	"case-of":  Case_of,
+	"try":      Try,
+	"while":    While,
+	"repeat":   Repeat,
+	"for":      For,
+	"break":    Break,
+	"continue": Continue,
+	"return":   Return,
 }
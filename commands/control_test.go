@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+func TestTry(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"try { + 1 2 }", "3"},
+		{"try { + 1 x } catch e { value caught }", "caught"},
+		{"try { raise oops } catch e { error-kind $e }", "oops"},
+		{"try { raise oops } catch e of nope oops { value 1 } " +
+			"catch e { value 2 }", "1"},
+		{"try { raise oops } catch e of nope { value 1 } catch e { value 2 }",
+			"2"},
+		{"set! n 0; try { + 1 2 } finally { set! n 1 }; value $n", "1"},
+		{"set! n 0; try { try { raise a } finally { set! n 1 } } catch e " +
+			"{ List $n [error-kind $e] }", "{1 a}"},
+		//the error replaces the one from the handler
+		{"try { try { raise a } catch e { raise b } } " +
+			"catch e { error-kind $e }", "b"},
+		{"try { try { raise a } finally { raise c } } " +
+			"catch e { error-kind $e }", "c"},
+		//the variable is put back as it was
+		{"set! e before; try { raise a } catch e { value 1 }; value $e",
+			"before"},
+		{"try { raise a } catch e { value 1 }; set? e", "false"},
+		//break runs the cleanup on its way out
+		{"set! n 0; while { = 1 1 } { try { break } finally { incr! n } }; " +
+			"value $n", "1"},
+	})
+	vm := _new_vm(t, false)
+	if k := gelo.ErrorKind(_eval_err(t, vm,
+		"try { raise oops } catch e of nope { value 1 }")); k != "oops" {
+		t.Errorf("an uncaught error came out as %s", k)
+	}
+	for _, src := range []string{"try", "try { } catch", "try { } finally",
+		"try { } catch e of { }", "try { } oops"} {
+		_eval_err(t, vm, src)
+	}
+}
+
+//nothing can be evaluated after these, not even the cleanup
+func TestTryFatal(t *testing.T) {
+	vm := _new_vm(t, false)
+	vm.SetStepLimit(200)
+	src := "set! n 0; try { set! f { f }; f } catch e { set! n 1 } " +
+		"finally { set! n 2 }"
+	if k := gelo.ErrorKind(_eval_err(t, vm, src)); k != gelo.ErrKindStepLimit {
+		t.Errorf("expected a step-limit error, got %s", k)
+	}
+	vm.SetStepLimit(0)
+	if got := _eval(t, vm, "value $n"); got != "0" {
+		t.Errorf("the step limit was caught or cleaned up after, n = %s", got)
+	}
+}
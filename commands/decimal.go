@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"code.google.com/p/gelo"
+	"code.google.com/p/gelo/extensions"
+	"strconv"
+)
+
+//The math commands work in decimals when any of their operands is one, see
+//decimal.go in gelo.
+
+var _decimal_one, _ = gelo.NewDecimalFromString("1")
+
+//the first decimal in args, or nil if there is none
+func _first_decimal(args *gelo.List) *gelo.Decimal {
+	for ; args != nil; args = args.Next {
+		if d, ok := args.Value.(*gelo.Decimal); ok {
+			return d
+		}
+	}
+	return nil
+}
+
+//w as a decimal, a number is converted as for an operand of like
+func _as_decimal(vm *gelo.VM, w gelo.Word, like *gelo.Decimal) *gelo.Decimal {
+	if d, ok := w.(*gelo.Decimal); ok {
+		return d
+	}
+	n := vm.API.NumberOrElse(w)
+	d, ok := gelo.NewDecimalFromNumber(n, like.Scale(), like.Mode())
+	if !ok {
+		gelo.RuntimeError(vm, "Cannot make a decimal of", w)
+	}
+	return d
+}
+
+func _rounding_mode(vm *gelo.VM, args map[string]gelo.Word,
+	def gelo.RoundingMode) gelo.RoundingMode {
+	w, ok := args["mode"]
+	if !ok {
+		return def
+	}
+	mode, ok := gelo.ParseRoundingMode(w.Ser().String())
+	if !ok {
+		gelo.RuntimeError(vm, "Rounding mode must be half-even, half-up,",
+			"half-down, up, down, ceiling or floor:", w)
+	}
+	return mode
+}
+
+func _decimal_scale(vm *gelo.VM, w gelo.Word) int {
+	if d, ok := w.(*gelo.Decimal); ok {
+		return d.Scale()
+	}
+	s, ok := vm.API.NumberOrElse(w).Int()
+	if !ok || s < 0 || s > gelo.MaxDecimalScale {
+		gelo.RuntimeError(vm, "Scale must be an integer from 0 to",
+			strconv.Itoa(gelo.MaxDecimalScale)+":", w)
+	}
+	return int(s)
+}
+
+//as _decimal_scale but also takes the scale of the text of a decimal with a
+//point in it, such as 0.01
+func _exemplar_scale(vm *gelo.VM, w gelo.Word) int {
+	if _, ok := w.(*gelo.Decimal); !ok {
+		if d, ok := gelo.NewDecimalFromString(w.Ser().String()); ok &&
+			d.Scale() > 0 {
+			return d.Scale()
+		}
+	}
+	return _decimal_scale(vm, w)
+}
+
+var _decimal_parser = extensions.MakeOrElseArgParser(
+	"value ['scale s]? ['rounding mode]?")
+
+//Makes a decimal of value, which is a decimal, a number or the text of a
+//decimal such as 12.50. Its scale is that of value unless one is given, in
+//which case value is rounded or padded to it.
+func DecimalCon(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	Args := _decimal_parser(vm, args)
+	value := Args["value"]
+	d, ok := value.(*gelo.Decimal)
+	mode := gelo.RoundHalfEven
+	if ok {
+		mode = d.Mode()
+	}
+	mode = _rounding_mode(vm, Args, mode)
+	if !ok {
+		var scale int
+		if s, given := Args["s"]; given {
+			scale = _decimal_scale(vm, s)
+		}
+		d, ok = gelo.NewDecimalFromString(value.Ser().String())
+		if !ok {
+			n := vm.API.NumberOrElse(value)
+			d, ok = gelo.NewDecimalFromNumber(n, scale, mode)
+			if !ok {
+				gelo.RuntimeError(vm, "Cannot make a decimal of", value)
+			}
+			if _, given := Args["s"]; !given && !d.Equals(n) {
+				gelo.RuntimeError(vm, value, "has no exact decimal, give a scale")
+			}
+		}
+	}
+	d = d.WithMode(mode)
+	if s, given := Args["s"]; given {
+		d = d.Quantize(_decimal_scale(vm, s), mode)
+	}
+	return d
+}
+
+var _round_parser = extensions.MakeOrElseArgParser(
+	"x [places]? ['rounding mode]?")
+
+//Rounds x to at most the given number of places after the point, 0 by default,
+//with the rounding mode of x if it is a decimal and half-even otherwise. x is
+//returned as the same kind of number it was given as.
+func Round(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	Args := _round_parser(vm, args)
+	var places int
+	if p, ok := Args["places"]; ok {
+		places = _decimal_scale(vm, p)
+	}
+	if d, ok := Args["x"].(*gelo.Decimal); ok {
+		return d.Round(places, _rounding_mode(vm, Args, d.Mode()))
+	}
+	mode := _rounding_mode(vm, Args, gelo.RoundHalfEven)
+	n := vm.API.NumberOrElse(Args["x"])
+	if r, exact := n.Rat(); exact {
+		return gelo.NewDecimalFromRat(r, places, mode).Number()
+	}
+	d, ok := gelo.NewDecimalFromNumber(n, places, mode)
+	if !ok {
+		//NaN and the infinities are their own rounding
+		return n
+	}
+	f, _ := strconv.ParseFloat(d.Round(places, mode).Ser().String(), 64)
+	return gelo.NewNumber(f)
+}
+
+var _quantize_parser = extensions.MakeOrElseArgParser(
+	"x scale ['rounding mode]?")
+
+//Returns x as a decimal with exactly scale places after the point, rounded or
+//padded with zeros. scale may also be a decimal to take the scale of, as in
+//quantize $x 0.01 for two places. Unlike an exemplar, a whole number is always
+//a count of places, so quantize $x 1 gives one place rather than none.
+func Quantize(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	Args := _quantize_parser(vm, args)
+	scale := _exemplar_scale(vm, Args["scale"])
+	x := Args["x"]
+	d, ok := x.(*gelo.Decimal)
+	if !ok {
+		mode := _rounding_mode(vm, Args, gelo.RoundHalfEven)
+		d, ok = gelo.NewDecimalFromNumber(vm.API.NumberOrElse(x), scale, mode)
+		if !ok {
+			gelo.RuntimeError(vm, "Cannot make a decimal of", x)
+		}
+	}
+	return d.Quantize(scale, _rounding_mode(vm, Args, d.Mode()))
+}
@@ -0,0 +1,62 @@
+package commands
+
+import "testing"
+
+func TestDecimalArithmetic(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"Decimal 12.50", "12.50"},
+		{"+ [Decimal 1.10] 2.205", "3.305"},
+		{"* [Decimal 1.5] [Decimal 0.25]", "0.375"},
+		{"- [Decimal 1.00] 0.1", "0.90"},
+		{"div [Decimal 1.00] 3", "0.33"},
+		{"= [Decimal 1.50] 1.5", "true"},
+	})
+	vm := _new_vm(t, false)
+	for _, src := range []string{"Decimal 1/3",
+		"Decimal 1.5 rounding sideways"} {
+		_eval_err(t, vm, src)
+	}
+}
+
+//for counts in decimals if a bound or the step is one, so it does not stop
+//short of a bound it only misses in binary
+func TestDecimalFor(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"set! l [List]; for i from [Decimal 0.1] to 0.3 by 0.1 { " +
+			"set! l [List @l $i] }; value $l", "{0.1 0.2 0.3}"},
+		{"set! l [List]; for i from 0 to 0.3 by [Decimal 0.1] { " +
+			"set! l [List @l $i] }; value $l", "{0 0.1 0.2 0.3}"},
+		{"set! l [List]; for i from [Decimal 1.0] to 0 { " +
+			"set! l [List @l $i] }; value $l", "{1.0 0.0}"},
+	})
+	vm := _new_vm(t, false)
+	_eval_err(t, vm, "for i from [Decimal 1] to 2 by 0 { }")
+}
+
+func TestDecimalRounding(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"Decimal 2.675 scale 2", "2.68"},
+		{"Decimal 2.665 scale 2", "2.66"},
+		{"Decimal 2.665 scale 2 rounding half-up", "2.67"},
+		{"round 2.5", "2"},
+		{"round 3.5", "4"},
+		{"round [Decimal 2.675] 2", "2.68"},
+		{"round 2.675 2 rounding down", "2.67"},
+	})
+}
+
+func TestQuantize(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"quantize 1.2 2", "1.20"},
+		{"quantize [Decimal 1.005] 2 rounding half-up", "1.01"},
+		//a decimal gives its scale, as an exemplar would
+		{"quantize 1.2 0.01", "1.20"},
+		{"quantize [Decimal 1.005] 0.01", "1.00"},
+		{"quantize 1.2 1e-3", "1.200"},
+		{"quantize 1.25 1.0", "1.2"},
+		{"quantize 1.25 [Decimal 0.1]", "1.2"},
+		//but a whole number is a count of places
+		{"quantize 1.25 1", "1.2"},
+	})
+	_eval_err(t, _new_vm(t, false), "quantize 1 -1")
+}
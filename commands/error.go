@@ -14,8 +14,7 @@ func SyntaxError(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
 		gelo.ArgumentError(vm, "SyntaxError", "error-msg+", "")
 	}
-	//TODO should get a line-no, etc to allow creation of good error message
-	gelo.SyntaxError(args)
+	gelo.SyntaxError(vm, args)
 	panic("Issue 65")
 }
 
@@ -37,8 +36,75 @@ func ArgumentError(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	panic("Issue 65")
 }
 
+//raise kind payload?
+//raises a runtime error of the given kind, the payload, a dict, is kept with
+//the error for error-data to return. Its message entry, if any, is the message
+//of the error, otherwise the kind is.
+func Raise(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 1 && ac != 2 {
+		gelo.ArgumentError(vm, "raise", "kind payload?", args)
+	}
+	kind := args.Value.Ser().String()
+	var data *gelo.Dict
+	var msg gelo.Word = args.Value
+	if ac == 2 {
+		data = vm.API.DictOrElse(args.Next.Value)
+		if m, ok := data.StrGet("message"); ok {
+			msg = m
+		}
+	}
+	gelo.KindedError(vm, kind, data, msg)
+	panic("Issue 65")
+}
+
+func Error_kind(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 1 {
+		gelo.ArgumentError(vm, "error-kind", "error", args)
+	}
+	return gelo.StrToSym(gelo.ErrorKind(vm.API.ErrorOrElse(args.Value)))
+}
+
+func Error_message(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 1 {
+		gelo.ArgumentError(vm, "error-message", "error", args)
+	}
+	e := vm.API.ErrorOrElse(args.Value)
+	if m, ok := e.(interface {
+		Message() string
+	}); ok {
+		return gelo.StrToSym(m.Message())
+	}
+	return gelo.StrToSym(e.Error())
+}
+
+//returns an empty dict if the error was raised without a payload
+func Error_data(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 1 {
+		gelo.ArgumentError(vm, "error-data", "error", args)
+	}
+	e, ok := vm.API.ErrorOrElse(args.Value).(*gelo.ErrRuntime)
+	if !ok || e.Data() == nil {
+		return gelo.NewDict()
+	}
+	return e.Data().DeepCopy()
+}
+
+//the id of the VM that raised the error
+func Error_from(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	if ac != 1 {
+		gelo.ArgumentError(vm, "error-from", "error", args)
+	}
+	n, _ := gelo.NewNumberFromGo(vm.API.ErrorOrElse(args.Value).From())
+	return n
+}
+
 var ErrorCommands = map[string]interface{}{
 	"die":               Die,
+	"raise":             Raise,
+	"error-kind":        Error_kind,
+	"error-message":     Error_message,
+	"error-data":        Error_data,
+	"error-from":        Error_from,
 	"SyntaxError":       SyntaxError,
 	"TypeMismatchError": TypeMismatchError,
 	"ArgumentError":     ArgumentError,
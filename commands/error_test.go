@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+func TestRaise(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"try { raise oops } catch e { error-message $e }", "oops"},
+		{"try { raise oops [Dict {{message \"went wrong\"} {code 7}}] } " +
+			"catch e { error-message $e }", "went wrong"},
+		{"try { raise oops [Dict {{code 7}}] } catch e " +
+			"{ dict [error-data $e] get code }", "7"},
+		{"try { raise oops } catch e { llength [dict [error-data $e] keys] }",
+			"0"},
+		{"try { + 1 x } catch e { error-kind $e }", "runtime-error"},
+		//the data is a copy
+		{"set! d [Dict {{code 7}}]; try { raise oops $d } catch e " +
+			"{ dict [error-data $e] set! code 8 }; dict $d get code", "7"},
+	})
+	vm := _new_vm(t, false)
+	err := _eval_err(t, vm, "raise oops [Dict {{code 7}}]")
+	r, ok := err.(*gelo.ErrRuntime)
+	if !ok || r.Kind() != "oops" {
+		t.Fatalf("raise oops raised %v", err)
+	}
+	if code, ok := r.Data().StrGet("code"); !ok || code.Ser().String() != "7" {
+		t.Errorf("raised with the data %v", r.Data())
+	}
+	for _, src := range []string{"raise", "raise a b c", "raise a notadict",
+		"error-kind", "error-kind notanerror"} {
+		err := _eval_err(t, vm, src)
+		if k := gelo.ErrorKind(err); k != gelo.ErrKindRuntime {
+			t.Errorf("%s raised a %s error", src, k)
+		}
+	}
+}
+
+func TestKindedError(t *testing.T) {
+	vm := _new_vm(t, false)
+	data := gelo.NewDict()
+	data.StrSet("path", gelo.StrToSym("/tmp/x"))
+	vm.RegisterFunc("open", func(vm *gelo.VM) {
+		gelo.KindedError(vm, "not-found", data, "no such file")
+	})
+	got := _eval(t, vm, "try { open } catch e of not-found "+
+		"{ List [error-message $e] [dict [error-data $e] get path] }")
+	if got != `{"no such file" /tmp/x}` {
+		t.Errorf("caught %s", got)
+	}
+}
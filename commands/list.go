@@ -27,6 +27,7 @@ func Make_list(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if !ok || length < 1 {
 		gelo.TypeMismatch(vm, "nonzero positive integer", "number")
 	}
+	vm.API.CheckListLength(length)
 	zero, list := Args["zero-value"], extensions.ListBuilder()
 	for i := int64(1); i < length; i++ {
 		list.Push(zero.Copy())
@@ -192,7 +193,6 @@ func Range(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	a, start := _rassnum(vm, d, "a")
 	b, _ := _rassnum(vm, d, "b")
 	i, step := _rassnum(vm, d, "i")
-	//XXX +n by -m should either error or have a sane default behavior
 	if !start {
 		a = 0
 	}
@@ -206,10 +206,20 @@ func Range(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if i == 0 {
 		gelo.RuntimeError(vm, "range step size cannot be 0")
 	}
+	//otherwise the range never ends, and the count checked against the quota
+	//below would be meaningless
+	if (a < b && i < 0) || (a > b && i > 0) {
+		gelo.RuntimeError(vm, "range step must go from", a, "toward", b)
+	}
 	if math.Abs(b-a) < math.Abs(i) {
 		n := gelo.NewNumber(0)
 		return gelo.NewList(n)
 	}
+	if count := math.Ceil(math.Abs(b-a) / math.Abs(i)); count < math.MaxInt64 {
+		vm.API.CheckListLength(int64(count))
+	} else {
+		vm.API.CheckListLength(math.MaxInt64)
+	}
 	n, _ := gelo.NewNumberFromGo(a)
 	list := extensions.ListBuilder(n)
 	var cmp func(a, b float64) bool
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"code.google.com/p/gelo"
+)
+
+func TestRange(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"range 5", "{0 1 2 3 4}"},
+		{"range 2 to 5", "{2 3 4}"},
+		{"range 5 to 2", "{5 4 3}"},
+		{"range 0 to 10 by 3", "{0 3 6 9}"},
+		{"range 10 to 0 by -3", "{10 7 4 1}"},
+	})
+}
+
+func TestRangeStepAgainstDirection(t *testing.T) {
+	vm := _new_vm(t, false)
+	vm.SetQuotas(gelo.Quotas{ListLength: 1000})
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for _, src := range []string{"range 0 to 10 by -1",
+			"range 10 to 0 by 1", "range 0 to 10 by 0"} {
+			if _, err := vm.Do(src); err == nil {
+				t.Errorf("%s: expected an error", src)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("range with a step away from its end did not stop")
+	}
+}
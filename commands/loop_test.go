@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoops(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"set! n 0; while { < $n 5 } { incr! n }; value $n", "5"},
+		{"set! n 0; while { < $n 0 } { incr! n }", ""},
+		{"repeat 3 { value @arguments }", "2"},
+		{"set! l [List]; repeat 3 { set! l [List @l @arguments] }; value $l",
+			"{0 1 2}"},
+		{"repeat 0 { value 1 }", ""},
+		{"set! s 0; for x in {1 2 3} { set! s [+ $s $x] }; value $s", "6"},
+		{"set! l [List]; for i from 1 to 3 { set! l [List @l $i] }; value $l",
+			"{1 2 3}"},
+		{"set! l [List]; for i from 3 to 1 { set! l [List @l $i] }; value $l",
+			"{3 2 1}"},
+		{"set! l [List]; for i from 0 to 1 by 1/2 { set! l [List @l $i] }; " +
+			"value $l", "{0 1/2 1}"},
+		{"for i from 1 to 0 by 1 { value $i }", ""},
+		//var is restored
+		{"set! x outer; for x in {a b} { value $x }; value $x", "outer"},
+		{"for x in {a b} { value $x }; set? x", "false"},
+	})
+}
+
+func TestBreakContinue(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"while { = 1 1 } { break done }", "done"},
+		{"repeat 10 { if [= @arguments 3] then { break } }", ""},
+		{"set! l [List]; for i from 1 to 5 { if [= [mod $i 2] 0] " +
+			"then { continue }; set! l [List @l $i] }; value $l", "{1 3 5}"},
+		//each breaks out of the innermost loop only
+		{"set! n 0; repeat 3 { repeat 3 { break }; incr! n }; value $n", "3"},
+		{"set! n 0; for x in {a b} { repeat 5 { incr! n; continue; " +
+			"incr! n } }; value $n", "10"},
+		//a command invoked from a loop can have loops of its own
+		{"set! f { repeat 3 { break y } }; repeat 2 { f }", "y"},
+	})
+	vm := _new_vm(t, false)
+	for _, src := range []string{"break", "continue",
+		"for i from 1 to 2 by 0 { }", "repeat x { }", "while"} {
+		_eval_err(t, vm, src)
+	}
+	//but not break out of the loop it is invoked from, as it cannot return
+	//from the command it is invoked from
+	for _, src := range []string{
+		"set! f { break x }; repeat 3 { f }",
+		"set! f { List 1 [break] }; repeat 2 { f }",
+		"set! f { continue }; repeat 2 { f; value z }",
+		"set! f { if [= 1 1] then { break x } }; repeat 3 { f; value z }",
+	} {
+		if err := _eval_err(t, vm, src); !strings.Contains(err.Error(),
+			"outside of a loop") {
+			t.Errorf("%s: got %v, want an error outside of a loop", src, err)
+		}
+	}
+}
@@ -20,14 +20,27 @@ func NumberCon(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	})
 }
 
+//Only ever operands. A number fills in its serialization the first time it is
+//asked for it, so one shared by every VM must not be given to scripts.
+var _one, _ = gelo.NewNumberFromGo(1)
+var _zero, _ = gelo.NewNumberFromGo(0)
+
+//a 0 of its own for a script
+func _new_zero() *gelo.Number {
+	n, _ := gelo.NewNumberFromGo(0)
+	return n
+}
+
 func Incrx(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac != 1 {
 		gelo.ArgumentError(vm, "incr!", "reference to number", args)
 	}
 	n, ok := vm.Ns.MutateBy(args.Value,
 		func(w gelo.Word) (gelo.Word, bool) {
-			n := vm.API.NumberOrElse(w)
-			return gelo.NewNumber(n.Real() + 1), true
+			if d, ok := w.(*gelo.Decimal); ok {
+				return d.Add(_decimal_one), true
+			}
+			return vm.API.NumberOrElse(w).Add(_one), true
 		})
 	if !ok {
 		gelo.VariableUndefined(vm, args.Value)
@@ -41,8 +54,10 @@ func Decrx(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	}
 	n, ok := vm.Ns.MutateBy(args.Value,
 		func(w gelo.Word) (gelo.Word, bool) {
-			n := vm.API.NumberOrElse(w)
-			return gelo.NewNumber(n.Real() - 1), true
+			if d, ok := w.(*gelo.Decimal); ok {
+				return d.Sub(_decimal_one), true
+			}
+			return vm.API.NumberOrElse(w).Sub(_one), true
 		})
 	if !ok {
 		gelo.VariableUndefined(vm, args.Value)
@@ -52,152 +67,145 @@ func Decrx(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 
 func Sum(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
-		return gelo.NewNumber(0)
+		return _new_zero()
+	}
+	if like := _first_decimal(args); like != nil {
+		acc := _as_decimal(vm, args.Value, like)
+		for args = args.Next; args != nil; args = args.Next {
+			acc = acc.Add(_as_decimal(vm, args.Value, like))
+		}
+		return acc
 	}
-	var acc float64
-	for ; args != nil; args = args.Next {
-		acc += vm.API.NumberOrElse(args.Value).Real()
+	acc := vm.API.NumberOrElse(args.Value)
+	for args = args.Next; args != nil; args = args.Next {
+		acc = acc.Add(vm.API.NumberOrElse(args.Value))
 	}
-	return gelo.NewNumber(acc)
+	return acc
 }
 
 func Product(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
-		return gelo.NewNumber(0)
+		return _new_zero()
+	}
+	if like := _first_decimal(args); like != nil {
+		acc := _as_decimal(vm, args.Value, like)
+		for args = args.Next; args != nil; args = args.Next {
+			acc = acc.Mul(_as_decimal(vm, args.Value, like))
+		}
+		return acc
 	}
-	var acc float64 = 1
-	for ; args != nil; args = args.Next {
-		acc *= vm.API.NumberOrElse(args.Value).Real()
+	acc := vm.API.NumberOrElse(args.Value)
+	for args = args.Next; args != nil; args = args.Next {
+		acc = acc.Mul(vm.API.NumberOrElse(args.Value))
 	}
-	return gelo.NewNumber(acc)
+	return acc
 }
 
 //Left-to-right
 func Difference(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
-		return gelo.NewNumber(0)
+		return _new_zero()
+	}
+	if like := _first_decimal(args); like != nil {
+		acc := _as_decimal(vm, args.Value, like)
+		for args = args.Next; args != nil; args = args.Next {
+			acc = acc.Sub(_as_decimal(vm, args.Value, like))
+		}
+		return acc
 	}
-	acc := vm.API.NumberOrElse(args.Value).Real()
+	acc := vm.API.NumberOrElse(args.Value)
 	for args = args.Next; args != nil; args = args.Next {
-		acc -= vm.API.NumberOrElse(args.Value).Real()
+		acc = acc.Sub(vm.API.NumberOrElse(args.Value))
 	}
-	return gelo.NewNumber(acc)
+	return acc
 }
 
 //Left-to-right
 func Quotient(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
-		return gelo.NewNumber(0)
+		return _new_zero()
+	}
+	if like := _first_decimal(args); like != nil {
+		acc := _as_decimal(vm, args.Value, like)
+		for args = args.Next; args != nil; args = args.Next {
+			d := _as_decimal(vm, args.Value, like)
+			if d.IsZero() {
+				gelo.RuntimeError(vm, "Division by 0")
+			}
+			acc = acc.Quo(d)
+		}
+		return acc
 	}
-	acc := vm.API.NumberOrElse(args.Value).Real()
+	acc := vm.API.NumberOrElse(args.Value)
 	for args = args.Next; args != nil; args = args.Next {
-		n := vm.API.NumberOrElse(args.Value).Real()
-		if n == 0 {
+		n := vm.API.NumberOrElse(args.Value)
+		if n.IsZero() {
 			gelo.RuntimeError(vm, "Division by 0")
 		}
-		acc /= n
+		acc = acc.Quo(n)
 	}
-	return gelo.NewNumber(acc)
+	return acc
 }
 
 func Mod(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac != 2 {
 		gelo.ArgumentError(vm, "mod", "number base", args)
 	}
-	n := vm.API.NumberOrElse(args.Value).Real()
-	m := vm.API.NumberOrElse(args.Next.Value).Real()
-	return gelo.NewNumber(math.Mod(n, m))
-}
-
-func Lt(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
-	if ac == 0 {
-		return gelo.False
-	} else if ac == 1 {
-		return gelo.True
-	}
-	last := vm.API.NumberOrElse(args.Value).Real()
-	cur := last
-	for args = args.Next; args != nil; args = args.Next {
-		cur = vm.API.NumberOrElse(args.Value).Real()
-		if last >= cur {
-			return gelo.False
+	if like := _first_decimal(args); like != nil {
+		m := _as_decimal(vm, args.Next.Value, like)
+		if m.IsZero() {
+			gelo.RuntimeError(vm, "Division by 0")
 		}
-		last = cur
-	}
-	return gelo.True
-}
-
-func Lte(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
-	if ac == 0 {
-		return gelo.False
-	} else if ac == 1 {
-		return gelo.True
+		return _as_decimal(vm, args.Value, like).Mod(m)
 	}
-	last := vm.API.NumberOrElse(args.Value).Real()
-	cur := last
-	for args = args.Next; args != nil; args = args.Next {
-		cur = vm.API.NumberOrElse(args.Value).Real()
-		if last > cur {
-			return gelo.False
-		}
-		last = cur
+	n := vm.API.NumberOrElse(args.Value)
+	m := vm.API.NumberOrElse(args.Next.Value)
+	if m.IsZero() {
+		gelo.RuntimeError(vm, "Division by 0")
 	}
-	return gelo.True
+	return n.Mod(m)
 }
 
-func Gt(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
-	if ac == 0 {
-		return gelo.False
-	} else if ac == 1 {
-		return gelo.True
-	}
-	last := vm.API.NumberOrElse(args.Value).Real()
-	cur := last
-	for args = args.Next; args != nil; args = args.Next {
-		cur = vm.API.NumberOrElse(args.Value).Real()
-		if last <= cur {
+//compares each number to the next with ordered, false if any is NaN
+func _compare_gen(ordered func(int) bool) gelo.Alien {
+	return func(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+		if ac == 0 {
 			return gelo.False
+		} else if ac == 1 {
+			return gelo.True
 		}
-		last = cur
-	}
-	return gelo.True
-}
-
-func Gte(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
-	if ac == 0 {
-		return gelo.False
-	} else if ac == 1 {
-		return gelo.True
-	}
-	last := vm.API.NumberOrElse(args.Value).Real()
-	cur := last
-	for args = args.Next; args != nil; args = args.Next {
-		cur = vm.API.NumberOrElse(args.Value).Real()
-		if last < cur {
-			return gelo.False
+		last := vm.API.NumberOrElse(args.Value)
+		for args = args.Next; args != nil; args = args.Next {
+			cur := vm.API.NumberOrElse(args.Value)
+			if c, ok := last.Cmp(cur); !ok || !ordered(c) {
+				return gelo.False
+			}
+			last = cur
 		}
-		last = cur
+		return gelo.True
 	}
-	return gelo.True
 }
 
+var Lt = _compare_gen(func(c int) bool { return c < 0 })
+var Lte = _compare_gen(func(c int) bool { return c <= 0 })
+var Gt = _compare_gen(func(c int) bool { return c > 0 })
+var Gte = _compare_gen(func(c int) bool { return c >= 0 })
+
 func Min(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
 		gelo.ArgumentError(vm, "min", "number+", args)
 	}
-	var min *gelo.Number
-	var minf, candidate float64
-	n := vm.API.NumberOrElse(args.Value)
-	min = n
-	minf = n.Real()
+	min, minw := vm.API.NumberOrElse(args.Value), args.Value
 	for args = args.Next; args != nil; args = args.Next {
-		n = vm.API.NumberOrElse(args.Value)
-		candidate = n.Real()
-		if candidate < minf {
-			min = n
-			minf = candidate
+		n := vm.API.NumberOrElse(args.Value)
+		if c, ok := n.Cmp(min); ok && c < 0 {
+			min, minw = n, args.Value
 		}
 	}
+	//as given, so decimals stay decimals
+	if _, ok := minw.(*gelo.Decimal); ok {
+		return minw
+	}
 	return min
 }
 
@@ -205,19 +213,17 @@ func Max(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if ac == 0 {
 		gelo.ArgumentError(vm, "min", "number+", args)
 	}
-	var max *gelo.Number
-	var maxf, candidate float64
-	n := vm.API.NumberOrElse(args.Value)
-	max = n
-	maxf = n.Real()
+	max, maxw := vm.API.NumberOrElse(args.Value), args.Value
 	for args = args.Next; args != nil; args = args.Next {
-		n = vm.API.NumberOrElse(args.Value)
-		candidate = n.Real()
-		if maxf < candidate {
-			max = n
-			maxf = candidate
+		n := vm.API.NumberOrElse(args.Value)
+		if c, ok := n.Cmp(max); ok && c > 0 {
+			max, maxw = n, args.Value
 		}
 	}
+	//as given, so decimals stay decimals
+	if _, ok := maxw.(*gelo.Decimal); ok {
+		return maxw
+	}
 	return max
 }
 
@@ -226,8 +232,10 @@ func Neg(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		return gelo.NewNumber(0)
 	}
 	return args.MapOrApply(func(w gelo.Word) gelo.Word {
-		n := vm.API.NumberOrElse(w).Real()
-		return gelo.NewNumber(-n)
+		if d, ok := w.(*gelo.Decimal); ok {
+			return d.Neg()
+		}
+		return vm.API.NumberOrElse(w).Neg()
 	})
 }
 
@@ -236,8 +244,10 @@ func Abs(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		return gelo.NewNumber(0)
 	}
 	return args.MapOrApply(func(w gelo.Word) gelo.Word {
-		n := vm.API.NumberOrElse(w).Real()
-		return gelo.NewNumber(math.Abs(n))
+		if d, ok := w.(*gelo.Decimal); ok {
+			return d.Abs()
+		}
+		return vm.API.NumberOrElse(w).Abs()
 	})
 }
 
@@ -246,16 +256,12 @@ func Sgn(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		gelo.ArgumentError(vm, "sgn", "number+", "")
 	}
 	return args.MapOrApply(func(w gelo.Word) gelo.Word {
-		n := vm.API.NumberOrElse(w).Real()
-		switch {
-		case n < 0:
-			n = -1
-		case n > 0:
-			n = 1
-		case n == 0:
-			n = 0
+		n := vm.API.NumberOrElse(w)
+		if math.IsNaN(n.Real()) {
+			return n
 		}
-		return gelo.NewNumber(n)
+		s, _ := gelo.NewNumberFromGo(n.Sign())
+		return s
 	})
 }
 
@@ -264,9 +270,7 @@ func Integerp(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		return gelo.False
 	}
 	return args.MapOrApply(func(w gelo.Word) gelo.Word {
-		n := vm.API.NumberOrElse(w)
-		_, ok := n.Int()
-		return gelo.ToBool(ok)
+		return gelo.ToBool(vm.API.NumberOrElse(w).IsInteger())
 	})
 }
 
@@ -275,7 +279,8 @@ func Positivep(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		return gelo.False
 	}
 	return args.MapOrApply(func(w gelo.Word) gelo.Word {
-		return gelo.ToBool(vm.API.NumberOrElse(w).Real() >= 0)
+		c, ok := vm.API.NumberOrElse(w).Cmp(_zero)
+		return gelo.ToBool(ok && c >= 0)
 	})
 }
 
@@ -284,7 +289,8 @@ func Negativep(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		return gelo.False
 	}
 	return args.MapOrApply(func(w gelo.Word) gelo.Word {
-		return gelo.ToBool(vm.API.NumberOrElse(w).Real() < 0)
+		c, ok := vm.API.NumberOrElse(w).Cmp(_zero)
+		return gelo.ToBool(ok && c < 0)
 	})
 }
 
@@ -330,6 +336,10 @@ var MathCommands = map[string]interface{}{
 	"abs":    Abs,
 	"sgn":    Sgn,
 	"neg":    Neg,
+	//decimals
+	"Decimal":  DecimalCon,
+	"round":    Round,
+	"quantize": Quantize,
 	//predicates
 	"integer?":  Integerp,
 	"positive?": Positivep,
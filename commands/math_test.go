@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestExactArithmetic(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"+", "0"},
+		{"*", "0"},
+		{"+ 18446744073709551615 1", "18446744073709551616"},
+		{"* 4294967296 4294967296", "18446744073709551616"},
+		{"- 1 18446744073709551616", "-18446744073709551615"},
+		{"div 1 3", "1/3"},
+		{"+ 1/3 1/6", "1/2"},
+		{"div 6 3", "2"},
+		{"* 2/3 3/2", "1"},
+		{"+ 1/2 0.25", "0.75"},
+		{"mod 7 -3", "1"},
+		{"mod -7/2 2", "-3/2"},
+		{"< 1/3 0.3333", "false"},
+		{"= 1/2 0.5", "true"},
+		{"max 1/3 0.3", "1/3"},
+		{"neg 1/3", "-1/3"},
+		{"abs -18446744073709551616", "18446744073709551616"},
+		{"sgn -1/3", "-1"},
+		{"integer? 4/2", "true"},
+		{"integer? 1/2", "false"},
+		{"set! n 18446744073709551615; incr! n; value $n", "18446744073709551616"},
+	})
+	vm := _new_vm(t, false)
+	for _, src := range []string{"div 1 0", "div 1/2 0", "mod 7 0",
+		"mod 7.5 0"} {
+		_eval_err(t, vm, src)
+	}
+}
+
+//the results are serialized from several goroutines at once, run with -race
+func TestArithmeticResultsNotShared(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vm := _new_vm(t, false)
+			for _, src := range []string{"+", "*", "-", "div"} {
+				ret, err := vm.Do(src)
+				if err != nil {
+					t.Errorf("%s: %v", src, err)
+				} else if ret.Ser().String() != "0" {
+					t.Errorf("%s = %s", src, ret.Ser())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -43,7 +43,7 @@ func PortWritex(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	} else {
 		msg = args.Next
 	}
-	p.Send(msg)
+	vm.API.SendTo(p, msg)
 	return msg
 }
 
@@ -55,7 +55,7 @@ func PortReadx(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	if p.Closed() {
 		gelo.RuntimeError(vm, "attempted to read from a closed port")
 	}
-	return p.Recv()
+	return vm.API.RecvFrom(p)
 }
 
 var PortCommands = map[string]interface{}{
@@ -85,7 +85,21 @@ func Re_replace(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	r := ReOrElse(vm, args.Value)
 	src := args.Next.Value.Ser().Bytes()
 	repl := args.Next.Next.Value.Ser().Bytes()
-	return gelo.BytesToSym(r.ReplaceAll(src, repl))
+	//as r.ReplaceAll, but checking the size of the result against the quota
+	//as it grows, as the replacements can make it far larger than src
+	size := int64(len(src))
+	var out []byte
+	last := 0
+	for _, m := range r.FindAllSubmatchIndex(src, -1) {
+		out = append(out, src[last:m[0]]...)
+		n := len(out)
+		out = r.Expand(out, repl, src, m)
+		size += int64(len(out)-n) - int64(m[1]-m[0])
+		vm.API.CheckSymbolSize(size)
+		last = m[1]
+	}
+	vm.API.CheckSymbolSize(size)
+	return gelo.BytesToSym(append(out, src[last:]...))
 }
 
 func Re_replace_by(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
@@ -95,10 +109,17 @@ func Re_replace_by(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	r := ReOrElse(vm, args.Value)
 	src := args.Next.Value.Ser().Bytes()
 	repl := args.Next.Next.Value
-	return gelo.BytesToSym(r.ReplaceAllFunc(src, func(s []byte) []byte {
+	//checked as in Re_replace
+	size := int64(len(src))
+	out := r.ReplaceAllFunc(src, func(s []byte) []byte {
 		args := gelo.NewList(gelo.BytesToSym(s))
-		return vm.API.InvokeCmdOrElse(repl, args).Ser().Bytes()
-	}))
+		b := vm.API.InvokeCmdOrElse(repl, args).Ser().Bytes()
+		size += int64(len(b) - len(s))
+		vm.API.CheckSymbolSize(size)
+		return b
+	})
+	vm.API.CheckSymbolSize(size)
+	return gelo.BytesToSym(out)
 }
 
 var RegexpCommands = map[string]interface{}{
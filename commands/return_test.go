@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+func TestReturn(t *testing.T) {
+	_eval_cases(t, false, []_eval_case{
+		{"set! f { if [= 1 1] then { return early }; value late }; f",
+			"early"},
+		{"set! f { return; value late }; f", ""},
+		{"set! f { for x in {1 2 3} { if [= $x 2] then { return $x } }; " +
+			"value none }; f", "2"},
+		//only from the innermost command
+		{"set! g { return inner }; set! f { g; value outer }; f", "outer"},
+		{"return 1; value 2", "1"},
+		//the cleanups run on the way out
+		{"set! d 0; set! f { defer set! d 1; return x }; List [f] $d",
+			"{x 1}"},
+		{"set! n 0; set! f { try { return x } finally { set! n 1 } }; " +
+			"List [f] $n", "{x 1}"},
+		{"set! f { try { return x } catch e { value caught } }; f", "x"},
+		//a return in a defer returns from the quote that registered it
+		{"set! f { defer return x; value y }; f", "x"},
+		{"set! f { defer return x; value y }; f; value z", "z"},
+		{"set! f { defer return x; value y }; List [f] z", "{x z}"},
+		{"defer return x; value y", "x"},
+	})
+	_eval_cases(t, true, []_eval_case{
+		{"command g {x} { if [= $x 1] then { return one }; value other }; " +
+			"List [g 1] [g 2]", "{one other}"},
+	})
+	_eval_err(t, _new_vm(t, false), "return a b")
+}
+
+//a quote invoked by an Alien is a command of its own
+func TestReturnInvoked(t *testing.T) {
+	vm := _new_vm(t, false)
+	vm.Register("call", func(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+		return gelo.NewList(vm.API.InvokeOrElse(args), gelo.StrToSym("after"))
+	})
+	got := _eval(t, vm, "set! f { call { return x; value y } }; f")
+	if got != "{x after}" {
+		t.Errorf("returned %s", got)
+	}
+}
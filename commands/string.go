@@ -81,10 +81,13 @@ func Join(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 	}
 	list := vm.API.ListOrElse(Args["list"])
 	slice := make([][]byte, list.Len())
+	size := int64(len(sep) * (len(slice) - 1))
 	for count := 0; list != nil; list = list.Next {
 		slice[count] = list.Value.Ser().Bytes()
+		size += int64(len(slice[count]))
 		count++
 	}
+	vm.API.CheckSymbolSize(size)
 	return gelo.BytesToSym(bytes.Join(slice, sep))
 }
 
@@ -30,6 +30,7 @@ var Listp, Dictp = _make_tpred("*LIST*"), _make_tpred("*DICT*")
 var Symbolp, Portp = _make_tpred("*SYMBOL*"), _make_tpred("*PORT*")
 var Quotep, Boolp = _make_tpred("*QUOTE*"), _make_tpred("*BOOL*")
 var Alienp, Nump = _make_tpred("*ALIEN*"), _make_tpred("*NUMBER*")
+var Decimalp = _make_tpred("*DECIMAL*")
 var Syntax_errorp = _make_tpred("*SYNTAX-ERROR*")
 var Runtime_errorp = _make_tpred("*RUNTIME-ERROR*")
 
@@ -46,6 +47,7 @@ var TypePredicates = map[string]interface{}{
 	"quote?":         Quotep,
 	"bool?":          Boolp,
 	"number?":        Nump,
+	"decimal?":       Decimalp,
 	"alien?":         Alienp,
 	"syntax-error?":  Syntax_errorp,
 	"runtime-error?": Runtime_errorp,
@@ -0,0 +1,45 @@
+package gelo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"code.google.com/p/gelo"
+)
+
+func TestDoContextCancelled(t *testing.T) {
+	vm := _new_vm()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := vm.DoContext(ctx, "+ 1 1")
+	if gelo.ErrorKind(err) != gelo.ErrKindCancelled {
+		t.Errorf("a cancelled context gave %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(),
+		20*time.Millisecond)
+	defer cancel()
+	_, err = vm.DoContext(ctx, _forever)
+	if gelo.ErrorKind(err) != gelo.ErrKindCancelled {
+		t.Fatalf("a timeout gave %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the error does not wrap the cause: %v", err)
+	}
+	//the VM can still be used afterwards
+	if got := _eval(t, vm, "+ 1 1"); got != "2" {
+		t.Errorf("+ 1 1 = %s", got)
+	}
+}
+
+func TestDoContextSpawned(t *testing.T) {
+	vm := _new_vm()
+	ctx, cancel := context.WithTimeout(context.Background(),
+		20*time.Millisecond)
+	defer cancel()
+	_, err := vm.DoContext(ctx, "safe-eval {"+_forever+"}; "+_forever)
+	if gelo.ErrorKind(err) != gelo.ErrKindCancelled {
+		t.Errorf("a timeout gave %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package gelo
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//Values of the types Convert has no case for are converted by the converter
+//registered for their type, if any, and otherwise:
+//
+//	time.Time              a symbol in RFC 3339 format
+//	time.Duration          a symbol such as 1m30s
+//	error, fmt.Stringer    a symbol of the message or string
+//	nil pointers           Null
+//	pointers               what they point to
+//	bools, numbers         a Bool or number, for types such as type Celsius float64
+//	strings                a symbol
+//	slices, arrays         a list of the converted elements
+//	maps                   a dict of the converted values, the keys must be strings
+//	structs                a dict of the converted fields, named as for NewObject
+//
+//Fields of structs that cannot be converted, such as channels and functions,
+//are left out of the dict. Anything else cannot be converted and Convert
+//raises a programmer error, as it does for values nested too deeply, which are
+//most likely cyclic.
+
+const _convert_max_depth = 1000
+
+type _iface_converter struct {
+	t reflect.Type
+	f func(interface{}) Word
+}
+
+var _converters struct {
+	mux    sync.RWMutex
+	exact  map[reflect.Type]func(interface{}) Word
+	ifaces []_iface_converter
+}
+
+//Registers f to convert the values of type t in Convert, and so wherever Go
+//values are made into words, such as the results of the Aliens made by
+//NewAlienFromFunc. If t is an interface type, f converts the values of the
+//types that implement it that have no converter of their own, trying
+//interfaces in the order they were registered. Registering a type again
+//replaces its converter and registering nil removes it. Types that Convert
+//has a case for, such as string and int, cannot be given a converter.
+func RegisterConverter(t reflect.Type, f func(interface{}) Word) {
+	c := &_converters
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if t.Kind() == reflect.Interface {
+		for i, ic := range c.ifaces {
+			if ic.t == t {
+				c.ifaces = append(c.ifaces[:i:i], c.ifaces[i+1:]...)
+				break
+			}
+		}
+		if f != nil {
+			c.ifaces = append(c.ifaces, _iface_converter{t, f})
+		}
+		return
+	}
+	if c.exact == nil {
+		c.exact = make(map[reflect.Type]func(interface{}) Word)
+	}
+	if f == nil {
+		delete(c.exact, t)
+	} else {
+		c.exact[t] = f
+	}
+}
+
+//the converter registered for t, if any
+func _converter(t reflect.Type) func(interface{}) Word {
+	c := &_converters
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	if f, ok := c.exact[t]; ok {
+		return f
+	}
+	for _, ic := range c.ifaces {
+		if t.Implements(ic.t) {
+			return ic.f
+		}
+	}
+	return nil
+}
+
+var (
+	_time_type     = reflect.TypeOf(time.Time{})
+	_duration_type = reflect.TypeOf(time.Duration(0))
+	_stringer_type = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+//whether Convert converts the values of type t, a struct or a pointer to one,
+//as something other than a dict of their fields
+func _converted_specially(t reflect.Type) bool {
+	if _converter(t) != nil || t == _time_type ||
+		t.Implements(_error_type) || t.Implements(_stringer_type) {
+		return true
+	}
+	return t.Kind() == reflect.Ptr && _converted_specially(t.Elem())
+}
+
+func _convert_reflect(v reflect.Value, depth int) Word {
+	if depth > _convert_max_depth {
+		programmerError(nil, "Convert given a value nested too deeply,",
+			"it may be cyclic")
+	}
+	t := v.Type()
+	if f := _converter(t); f != nil {
+		return f(v.Interface())
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return Null
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return interns(x.Format(time.RFC3339Nano))
+	case time.Duration:
+		return interns(x.String())
+	case error:
+		return interns(x.Error())
+	case fmt.Stringer:
+		return interns(x.String())
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		return _convert(v.Elem().Interface(), depth+1)
+	case reflect.Bool:
+		return ToBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n, _ := NewNumberFromGo(v.Int())
+		return n
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		n, _ := NewNumberFromGo(v.Uint())
+		return n
+	case reflect.Float32, reflect.Float64:
+		n, _ := NewNumberFromGo(v.Float())
+		return n
+	case reflect.String:
+		return interns(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return BytesToSym(dup(v.Bytes()))
+		}
+		return _list_from_value(v, depth)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		d := make(map[string]Word, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			d[iter.Key().String()] = _convert(iter.Value().Interface(), depth+1)
+		}
+		return &Dict{rep: d}
+	case reflect.Struct:
+		d := make(map[string]Word)
+		for _, sf := range reflect.VisibleFields(t) {
+			name, _, ok := _field_tag(sf)
+			if !ok || !_can_convert_result(sf.Type) {
+				continue
+			}
+			f, err := v.FieldByIndexErr(sf.Index)
+			if err != nil {
+				//in a nil embedded pointer
+				continue
+			}
+			d[name] = _convert(f.Interface(), depth+1)
+		}
+		return &Dict{rep: d}
+	}
+	programmerError(nil, "Convert given unknown type", t)
+	return nil
+}
+
+func _list_from_value(v reflect.Value, depth int) *List {
+	var head, tail *List
+	for i := 0; i < v.Len(); i++ {
+		l := &List{_convert(v.Index(i).Interface(), depth+1), nil}
+		if head == nil {
+			head = l
+		} else {
+			tail.Next = l
+		}
+		tail = l
+	}
+	return head
+}
@@ -0,0 +1,121 @@
+package gelo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.google.com/p/gelo"
+)
+
+type _celsius float64
+
+type _color int
+
+func (c _color) String() string { return [...]string{"red", "green"}[c] }
+
+type _point struct {
+	X, Y  int
+	Label string `gelo:"label"`
+	Skip  int    `gelo:"-"`
+	C     chan int
+}
+
+type _node struct {
+	Next *_node
+}
+
+func TestConvert(t *testing.T) {
+	n := 3
+	for _, c := range []struct {
+		in   interface{}
+		want string
+	}{
+		{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), "2020-01-02T03:04:05Z"},
+		{90 * time.Second, "1m30s"},
+		{errors.New("failed"), "failed"},
+		{_color(1), "green"},
+		{(*int)(nil), ""},
+		{&n, "3"},
+		{_celsius(21.5), "21.5"},
+		{[]int{1, 2}, "{1 2}"},
+		{[2]bool{true, false}, "{true false}"},
+		{[]_color{0, 1}, "{red green}"},
+		{map[string]int{"a": 1}, "{{a 1}}"},
+	} {
+		if got := gelo.Convert(c.in).Ser().String(); got != c.want {
+			t.Errorf("Convert(%#v) = %s, want %s", c.in, got, c.want)
+		}
+	}
+	d, ok := gelo.Convert(_point{X: 1, Y: 2, Label: "p", Skip: 3}).(*gelo.Dict)
+	if !ok {
+		t.Fatal("a struct was not converted to a dict")
+	}
+	m := d.Map()
+	if len(m) != 3 || m["X"].Ser().String() != "1" ||
+		m["label"].Ser().String() != "p" {
+		t.Errorf("converted the struct to %s", d.Ser())
+	}
+}
+
+func _convert_panics(in interface{}) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	gelo.Convert(in)
+	return
+}
+
+func TestConvertFails(t *testing.T) {
+	cycle := &_node{}
+	cycle.Next = cycle
+	for _, in := range []interface{}{map[int]int{1: 2}, make(chan int),
+		cycle} {
+		if !_convert_panics(in) {
+			t.Errorf("converted %T", in)
+		}
+	}
+}
+
+type _temp struct {
+	deg float64
+}
+
+type _named interface {
+	Name() string
+}
+
+type _thing struct{}
+
+func (_thing) Name() string { return "thing" }
+
+func TestRegisterConverter(t *testing.T) {
+	tt := reflect.TypeOf(_temp{})
+	gelo.RegisterConverter(tt, func(v interface{}) gelo.Word {
+		return gelo.StrToSym("hot")
+	})
+	defer gelo.RegisterConverter(tt, nil)
+	nt := reflect.TypeOf((*_named)(nil)).Elem()
+	gelo.RegisterConverter(nt, func(v interface{}) gelo.Word {
+		return gelo.StrToSym(v.(_named).Name())
+	})
+	defer gelo.RegisterConverter(nt, nil)
+	for _, c := range []struct {
+		in   interface{}
+		want string
+	}{
+		{_temp{40}, "hot"},
+		{&_temp{40}, "hot"},
+		{[]_temp{{1}}, "{hot}"},
+		{_thing{}, "thing"},
+	} {
+		if got := gelo.Convert(c.in).Ser().String(); got != c.want {
+			t.Errorf("Convert(%#v) = %s, want %s", c.in, got, c.want)
+		}
+	}
+	gelo.RegisterConverter(tt, nil)
+	if _, ok := gelo.Convert(_temp{}).(*gelo.Dict); !ok {
+		t.Error("the converter was not removed")
+	}
+}
@@ -0,0 +1,219 @@
+package gelo
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+//Coverage is recorded per command as it was parsed. The first time a VM that
+//is recording coverage invokes a quote, every command in the quote is
+//registered, along with the commands of the quotes written in it, so those
+//that are never run are reported too. Each command counts toward the line it
+//starts on, once each time it runs. A clause counts toward the command it is
+//in, unless it starts on a line of its own, so a line run n times reports n
+//however many clauses are on it. Quotes built at runtime have no lines, so their commands are
+//numbered in the order they appear and reported as if they were in a file
+//named for the quote.
+//
+//As registering cannot tell code from data, a quote used as data that spans
+//lines of its own, such as a large dict, is reported as lines that never ran.
+
+type _coverage struct {
+	lines  map[string]map[int]*int64
+	nodes  map[*sNode]*int64 //the head of each command to its line
+	quotes map[*quote]bool   //registered
+	anon   map[string]string //source of quotes built at runtime to file name
+}
+
+//where the commands of a quote being registered are counted
+type _cover_src struct {
+	file string //set if the quote has no positions
+	ord  int
+}
+
+//Start recording which commands vm evaluates, discarding any coverage
+//already being recorded. Only the commands vm evaluates are recorded, not
+//those of the VMs it spawns.
+//Call before running vm or from its goroutine, such as from an Alien.
+func (vm *VM) StartCoverage() {
+	vm._sanity("start recording coverage")
+	vm.cover = &_coverage{
+		lines:  make(map[string]map[int]*int64),
+		nodes:  make(map[*sNode]*int64),
+		quotes: make(map[*quote]bool),
+		anon:   make(map[string]string),
+	}
+	if vm.program != nil {
+		vm.cover._quote(vm.program)
+	}
+}
+
+//Stop recording coverage and return what was recorded, or nil if vm was not
+//recording coverage.
+//Call from the same goroutine as StartCoverage.
+func (vm *VM) StopCoverage() *Coverage {
+	c := vm.cover
+	if c == nil {
+		return nil
+	}
+	vm.cover = nil
+	out := &Coverage{make(map[string]map[int]int64)}
+	for file, lines := range c.lines {
+		hits := make(map[int]int64, len(lines))
+		for line, n := range lines {
+			hits[line] = *n
+		}
+		out.files[file] = hits
+	}
+	return out
+}
+
+//called with the instruction that invokes a command
+func (c *_coverage) _hit(src *sNode) {
+	if n, ok := c.nodes[src]; ok {
+		*n++
+	}
+}
+
+//quotes made by build_quote_from_list invoke a list, they are not code
+func (q *quote) _from_list() bool {
+	c := q.compiled
+	return c != nil && len(c.instrs) != 0 && c.instrs[0].src == nil
+}
+
+func (c *_coverage) _quote(q *quote) {
+	if c.quotes[q] || q._from_list() {
+		return
+	}
+	c.quotes[q] = true
+	cmds, ok := q.fcode()
+	if !ok || cmds == nil {
+		return
+	}
+	src := &_cover_src{}
+	if !q.pos.Known() {
+		name, ok := c.anon[string(q.source)]
+		if !ok {
+			name = fmt.Sprintf("<quote %d>", len(c.anon)+1)
+			c.anon[string(q.source)] = name
+		}
+		src.file = name
+	}
+	c._commands(cmds, src)
+}
+
+func (c *_coverage) _commands(cmds *command, src *_cover_src) {
+	for ; cmds != nil; cmds = cmds.next {
+		c._line(cmds.cmd, src, nil)
+	}
+}
+
+//in is the count of the command line is a clause of, nil if it is not one
+func (c *_coverage) _line(line *sNode, src *_cover_src, in *int64) {
+	if line == nil {
+		return
+	}
+	var file string
+	var n int
+	if src.file != "" {
+		if in == nil {
+			src.ord++
+		}
+		file, n = src.file, src.ord
+	} else if line.pos.Known() {
+		file, n = line.pos.File, line.pos.Line
+		if file == "" {
+			file = "<unnamed>"
+		}
+	} else {
+		return
+	}
+	lines, ok := c.lines[file]
+	if !ok {
+		lines = make(map[int]*int64)
+		c.lines[file] = lines
+	}
+	count, ok := lines[n]
+	if !ok {
+		count = new(int64)
+		lines[n] = count
+	}
+	if count != in {
+		c.nodes[line] = count
+	}
+	for w := line; w != nil; w = w.next {
+		c._word(w, src, count)
+	}
+}
+
+func (c *_coverage) _word(w *sNode, src *_cover_src, in *int64) {
+	switch w.tag {
+	case synQuote:
+		c._quote(w.val.(Quote).unprotect())
+	case synClause:
+		c._line(w.val.(*sNode), src, in)
+	case synIndirect, synSplice:
+		c._word(w.val.(*sNode), src, in)
+	}
+}
+
+//Coverage is what a VM recorded between StartCoverage and StopCoverage.
+type Coverage struct {
+	files map[string]map[int]int64
+}
+
+//Returns the names of the files with commands in the coverage, sorted
+func (c *Coverage) Files() []string {
+	out := make([]string, 0, len(c.files))
+	for file := range c.files {
+		out = append(out, file)
+	}
+	sort.Strings(out)
+	return out
+}
+
+//Returns how many times the commands on each line of file ran, including
+//the lines whose commands never did
+func (c *Coverage) Hits(file string) map[int]int64 {
+	out := make(map[int]int64, len(c.files[file]))
+	for line, n := range c.files[file] {
+		out[line] = n
+	}
+	return out
+}
+
+//Writes the coverage as an lcov tracefile, as read by genhtml and most tools
+//that report coverage
+func (c *Coverage) WriteLcov(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "TN:"); err != nil {
+		return err
+	}
+	for _, file := range c.Files() {
+		hits := c.files[file]
+		lines := make([]int, 0, len(hits))
+		for line := range hits {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		if _, err := fmt.Fprintf(w, "SF:%s\n", file); err != nil {
+			return err
+		}
+		hit := 0
+		for _, line := range lines {
+			if hits[line] != 0 {
+				hit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", line,
+				hits[line]); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines),
+			hit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
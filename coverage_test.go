@@ -0,0 +1,73 @@
+package gelo_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+const _cover_src = `set! n 0
+set! f {
+	set! n [+ $n 1]
+}
+if [= $n 1] then {
+	set! never 1
+}
+f
+f
+`
+
+func _cover(t *testing.T, src string) *gelo.Coverage {
+	t.Helper()
+	vm := _new_vm()
+	vm.StartCoverage()
+	if _, err := vm.Run("cover.gel", strings.NewReader(src),
+		nil); err != nil {
+		t.Fatal(err)
+	}
+	return vm.StopCoverage()
+}
+
+func TestCoverage(t *testing.T) {
+	c := _cover(t, _cover_src)
+	if files := c.Files(); len(files) != 1 || files[0] != "cover.gel" {
+		t.Fatalf("files %v", files)
+	}
+	want := map[int]int64{1: 1, 2: 1, 3: 2, 5: 1, 6: 0, 8: 1, 9: 1}
+	if got := c.Hits("cover.gel"); !reflect.DeepEqual(got, want) {
+		t.Errorf("hits %v, want %v", got, want)
+	}
+}
+
+func TestCoverageLcov(t *testing.T) {
+	var out bytes.Buffer
+	if err := _cover(t, _cover_src).WriteLcov(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "TN:\nSF:cover.gel\nDA:1,1\nDA:2,1\nDA:3,2\nDA:5,1\nDA:6,0\n" +
+		"DA:8,1\nDA:9,1\nLF:7\nLH:6\nend_of_record\n"
+	if out.String() != want {
+		t.Errorf("lcov\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+//a line counts once each time it runs, however many clauses are on it, but a
+//clause that starts on a later line than its command counts toward that line
+func TestCoverageClauses(t *testing.T) {
+	src := "set! n 0\nrepeat 200 {\n\tset! n [+ $n [* 1 [- 2 1]]]\n}\n" +
+		"List {\n} [- 2 1]\n"
+	want := map[int]int64{1: 1, 2: 1, 3: 200, 5: 1, 6: 1}
+	if got := _cover(t, src).Hits("cover.gel"); !reflect.DeepEqual(got, want) {
+		t.Errorf("hits %v, want %v", got, want)
+	}
+}
+
+func TestCoverageStop(t *testing.T) {
+	vm := _new_vm()
+	if vm.StopCoverage() != nil {
+		t.Error("StopCoverage returned coverage without StartCoverage")
+	}
+}
@@ -0,0 +1,370 @@
+package gelo
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+//A decimal is a number in base 10 with a fixed number of digits after the
+//point, its scale, so that 0.1 + 0.2 is exactly 0.3. Each decimal also has the
+//rounding mode used when a result has to be rounded to fit its scale.
+//
+//Sums and differences have the larger scale of their operands and products the
+//sum of the scales, so neither is ever rounded. Quotients have the larger
+//scale of their operands and are rounded with the mode of the dividend.
+//A number used with a decimal is converted to a decimal exactly if it can be,
+//so 0.2 is 0.2 and 1/4 is 0.25, and otherwise rounded to the scale of the
+//decimal, as 1/3 is. The rounding mode of a result is that of its left
+//operand.
+//
+//A decimal is serialized with exactly scale digits after the point, so 1.50
+//stays 1.50, and NewDecimalFromString reads that back as the same value and
+//scale. The rounding mode is not part of the serialization but is kept in
+//snapshots.
+
+type RoundingMode uint8
+
+const (
+	RoundHalfEven RoundingMode = iota //to nearest, ties to even, the default
+	RoundHalfUp                       //to nearest, ties away from zero
+	RoundHalfDown                     //to nearest, ties toward zero
+	RoundUp                           //away from zero
+	RoundDown                         //toward zero
+	RoundCeiling                      //toward +Inf
+	RoundFloor                        //toward -Inf
+)
+
+var _rounding_modes = [...]string{"half-even", "half-up", "half-down", "up",
+	"down", "ceiling", "floor"}
+
+//The name of the mode in gelo, such as half-even
+func (m RoundingMode) String() string {
+	if int(m) < len(_rounding_modes) {
+		return _rounding_modes[m]
+	}
+	return "RoundingMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+//Returns the mode named s, as by String
+func ParseRoundingMode(s string) (RoundingMode, bool) {
+	for i, name := range _rounding_modes {
+		if name == s {
+			return RoundingMode(i), true
+		}
+	}
+	return 0, false
+}
+
+//The largest scale a decimal read from a string or rounded by a command may
+//have, so a typo cannot make a decimal of millions of digits
+const MaxDecimalScale = 1 << 16
+
+var _big_ten = big.NewInt(10)
+
+func _pow10(n int) *big.Int {
+	return new(big.Int).Exp(_big_ten, big.NewInt(int64(n)), nil)
+}
+
+//Returns unscaled / 10^scale, unscaled is copied. scale must not be negative.
+func NewDecimal(unscaled *big.Int, scale int, mode RoundingMode) *Decimal {
+	if scale < 0 {
+		programmerError(nil, "NewDecimal given a negative scale:", scale)
+	}
+	return &Decimal{new(big.Int).Set(unscaled), scale, mode}
+}
+
+func NewDecimalFrom(w Word) (*Decimal, bool) {
+	if d, ok := w.(*Decimal); ok {
+		return d, true
+	}
+	return NewDecimalFromString(w.Ser().String())
+}
+
+//Reads a decimal such as -12.50 or 1.5e-3, whose scale is the number of digits
+//written after the point less the exponent, rounding half to even
+func NewDecimalFromString(s string) (*Decimal, bool) {
+	mant, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e > MaxDecimalScale || e < -MaxDecimalScale {
+			return nil, false
+		}
+		mant, exp = s[:i], e
+	}
+	ip, fp, _ := strings.Cut(mant, ".")
+	if strings.ContainsAny(fp, "+-") {
+		return nil, false
+	}
+	u, ok := new(big.Int).SetString(ip+fp, 10)
+	if !ok {
+		return nil, false
+	}
+	scale := len(fp) - exp
+	if scale > MaxDecimalScale {
+		return nil, false
+	}
+	if scale < 0 {
+		u.Mul(u, _pow10(-scale))
+		scale = 0
+	}
+	return &Decimal{u, scale, RoundHalfEven}, true
+}
+
+//Returns n as a decimal, exactly if it can be and otherwise rounded to scale
+//with mode, or ok false if n is NaN or infinite
+func NewDecimalFromNumber(n *Number, scale int,
+	mode RoundingMode) (*Decimal, bool) {
+	r, exact := n.Rat()
+	if !exact {
+		f := n.Real()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		//the serialization is the shortest decimal that reads as f
+		if d, ok := NewDecimalFromString(n.Ser().String()); ok {
+			d.mode = mode
+			return d, true
+		}
+		r = new(big.Rat).SetFloat64(f)
+	}
+	if s, ok := _decimal_places(r); ok {
+		scale = s
+	}
+	return NewDecimalFromRat(r, scale, mode), true
+}
+
+//Returns r rounded to scale with mode
+func NewDecimalFromRat(r *big.Rat, scale int, mode RoundingMode) *Decimal {
+	if scale < 0 {
+		programmerError(nil, "NewDecimalFromRat given a negative scale:", scale)
+	}
+	num := new(big.Int).Mul(r.Num(), _pow10(scale))
+	return &Decimal{_round_quo(num, r.Denom(), mode), scale, mode}
+}
+
+//the number of decimal places r needs, or ok false if it has no end
+func _decimal_places(r *big.Rat) (int, bool) {
+	d := new(big.Int).Set(r.Denom())
+	twos, fives := _remove_factor(d, 2), _remove_factor(d, 5)
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+//divides d by f for as long as f divides it, returning how many times it did
+func _remove_factor(d *big.Int, f int64) int {
+	bf, q, m := big.NewInt(f), new(big.Int), new(big.Int)
+	n := 0
+	for {
+		q.QuoRem(d, bf, m)
+		if m.Sign() != 0 {
+			return n
+		}
+		d.Set(q)
+		n++
+	}
+}
+
+//Returns n / d rounded to an integer with mode
+func _round_quo(n, d *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	//the sign of the exact quotient, q may be zero
+	sign := n.Sign() * d.Sign()
+	var away bool
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	default:
+		r.Abs(r)
+		switch r.Lsh(r, 1).Cmp(new(big.Int).Abs(d)) {
+		case 1:
+			away = true
+		case -1:
+			away = false
+		default:
+			switch mode {
+			case RoundHalfUp:
+				away = true
+			case RoundHalfDown:
+				away = false
+			default:
+				away = q.Bit(0) == 1
+			}
+		}
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return q
+}
+
+func (d *Decimal) Scale() int {
+	return d.scale
+}
+
+func (d *Decimal) Mode() RoundingMode {
+	return d.mode
+}
+
+//Returns the value of d times 10 to its scale
+func (d *Decimal) Unscaled() *big.Int {
+	return new(big.Int).Set(d.unscaled)
+}
+
+func (d *Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled, _pow10(d.scale))
+}
+
+//Returns d as an exact number
+func (d *Decimal) Number() *Number {
+	return _exact_rat(d.Rat())
+}
+
+func (d *Decimal) Sign() int {
+	return d.unscaled.Sign()
+}
+
+func (d *Decimal) IsZero() bool {
+	return d.unscaled.Sign() == 0
+}
+
+//Returns d with the given scale, rounded with mode if that is smaller than the
+//scale of d and padded with zeros if it is larger
+func (d *Decimal) Quantize(scale int, mode RoundingMode) *Decimal {
+	if scale < 0 {
+		programmerError(nil, "Quantize given a negative scale:", scale)
+	}
+	if scale >= d.scale {
+		u := new(big.Int).Mul(d.unscaled, _pow10(scale-d.scale))
+		return &Decimal{u, scale, d.mode}
+	}
+	u := _round_quo(d.unscaled, _pow10(d.scale-scale), mode)
+	return &Decimal{u, scale, d.mode}
+}
+
+//Returns d rounded with mode to at most scale digits after the point
+func (d *Decimal) Round(scale int, mode RoundingMode) *Decimal {
+	if scale >= d.scale {
+		return d
+	}
+	return d.Quantize(scale, mode)
+}
+
+//Returns d with another rounding mode
+func (d *Decimal) WithMode(mode RoundingMode) *Decimal {
+	return &Decimal{d.unscaled, d.scale, mode}
+}
+
+//both unscaled values at the larger scale of d and o
+func (d *Decimal) _align(o *Decimal) (a, b *big.Int, scale int) {
+	switch {
+	case d.scale < o.scale:
+		return new(big.Int).Mul(d.unscaled, _pow10(o.scale-d.scale)),
+			o.unscaled, o.scale
+	case d.scale > o.scale:
+		return d.unscaled,
+			new(big.Int).Mul(o.unscaled, _pow10(d.scale-o.scale)), d.scale
+	}
+	return d.unscaled, o.unscaled, d.scale
+}
+
+func (d *Decimal) Add(o *Decimal) *Decimal {
+	a, b, scale := d._align(o)
+	return &Decimal{new(big.Int).Add(a, b), scale, d.mode}
+}
+
+func (d *Decimal) Sub(o *Decimal) *Decimal {
+	a, b, scale := d._align(o)
+	return &Decimal{new(big.Int).Sub(a, b), scale, d.mode}
+}
+
+func (d *Decimal) Mul(o *Decimal) *Decimal {
+	return &Decimal{new(big.Int).Mul(d.unscaled, o.unscaled),
+		d.scale + o.scale, d.mode}
+}
+
+//Returns d / o rounded to the larger of their scales with the mode of d.
+//o must not be zero.
+func (d *Decimal) Quo(o *Decimal) *Decimal {
+	a, b, scale := d._align(o)
+	//(a / 10^s) / (b / 10^s) * 10^s
+	num := new(big.Int).Mul(a, _pow10(scale))
+	return &Decimal{_round_quo(num, b, d.mode), scale, d.mode}
+}
+
+//Returns the remainder of d / o truncated toward zero, which has the sign of d.
+//o must not be zero.
+func (d *Decimal) Mod(o *Decimal) *Decimal {
+	a, b, scale := d._align(o)
+	return &Decimal{new(big.Int).Rem(a, b), scale, d.mode}
+}
+
+func (d *Decimal) Neg() *Decimal {
+	return &Decimal{new(big.Int).Neg(d.unscaled), d.scale, d.mode}
+}
+
+func (d *Decimal) Abs() *Decimal {
+	return &Decimal{new(big.Int).Abs(d.unscaled), d.scale, d.mode}
+}
+
+//Returns -1, 0 or 1 as d is less than, equal to or greater than o, whatever
+//their scales
+func (d *Decimal) Cmp(o *Decimal) int {
+	a, b, _ := d._align(o)
+	return a.Cmp(b)
+}
+
+func (d *Decimal) Ser() Symbol {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		point := len(digits) - d.scale
+		digits = digits[:point] + "." + digits[point:]
+	}
+	if d.unscaled.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return StrToSym(digits)
+}
+
+//Decimals equal decimals and numbers of the same value, whatever their scale
+func (d *Decimal) Equals(w Word) bool {
+	switch o := w.(type) {
+	case *Decimal:
+		return d.Cmp(o) == 0
+	case *Number:
+		c, ok := d.Number().Cmp(o)
+		return ok && c == 0
+	}
+	return false
+}
+
+func (d *Decimal) Copy() Word {
+	//the unscaled value is never changed so it can be shared
+	return &Decimal{d.unscaled, d.scale, d.mode}
+}
+
+func (d *Decimal) DeepCopy() Word {
+	return d.Copy()
+}
+
+func (*Decimal) Type() Symbol {
+	return interns("*DECIMAL*")
+}
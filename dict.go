@@ -29,7 +29,7 @@ func UnserializeDict(ser []byte, enc bool) (*Dict, bool) {
 		return NewDict(), true
 	}
 	if enc {
-		if ser[pos] != '{' {
+		if pos >= len(ser) || ser[pos] != '{' {
 			return nil, false
 		}
 		pos = SlurpWS(ser, pos+1)
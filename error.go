@@ -5,10 +5,10 @@ import (
 	"fmt"
 )
 
-//hopefully one day we also have name, lineno, etc
 type _error struct {
 	from uint32
 	msg  string
+	pos  SrcPos
 }
 
 //we do not let _errSystem become a gelo.Error but we do want to piggyback
@@ -34,8 +34,27 @@ type ErrSyntax struct {
 
 type ErrRuntime struct {
 	_error
+	kind   string
+	frames []Frame
+	cause  error
+	data   *Dict //given by whoever raised the error, nil if none
 }
 
+//Kinds of runtime errors raised by the VM itself. See (*ErrRuntime).Kind
+const (
+	ErrKindRuntime   = "runtime-error"
+	ErrKindStepLimit = "step-limit"
+	ErrKindCancelled = "cancelled"
+	ErrKindImport    = "import-error" //See (*VM).SetModulePath
+	ErrKindSyntax    = "syntax-error" //Not a runtime error, see ErrorKind
+
+	//See Quotas
+	ErrKindListQuota   = "list-length-quota"
+	ErrKindSymbolQuota = "symbol-size-quota"
+	ErrKindChildQuota  = "child-quota"
+	ErrKindDepthQuota  = "namespace-depth-quota"
+)
+
 //Predefined kinds of errors
 
 func programmerError(vm *VM, s ...interface{}) {
@@ -62,22 +81,43 @@ Thank you for your time and the Gelo team deeply apologizes for the inconvience.
 	panic(_errSystem{_make_errorM(vm, x...)})
 }
 
+//If the first argument is the *VM raising the error, the error records the
+//VM and the position it was evaluating
 func SyntaxError(s ...interface{}) {
-	panic(&ErrSyntax{_make_error(nil, s)})
+	var vm *VM
+	if len(s) > 1 {
+		if v, ok := s[0].(*VM); ok {
+			vm, s = v, s[1:]
+		}
+	}
+	raise(vm, &ErrSyntax{_make_error(vm, s)})
+}
+
+//gives the hook of vm, if any, a look at e before it unwinds the stack
+func raise(vm *VM, e Error) {
+	vm._hook_error(e)
+	panic(e)
 }
 
 func VariableUndefined(vm *VM, name interface{}) {
-	panic(&ErrRuntime{_make_errorM(vm, "Undefined variable:", name)})
+	raise(vm, _make_runtime_error(vm, "Undefined variable:", name))
 }
 
 func RuntimeError(vm *VM, s ...interface{}) {
-	panic(&ErrRuntime{_make_error(vm, s)})
+	raise(vm, _make_runtime_error(vm, s))
+}
+
+//Raise a runtime error of the given kind carrying data, which may be nil, for
+//handlers to inspect
+func KindedError(vm *VM, kind string, data *Dict, s ...interface{}) {
+	e := _make_kinded_error(vm, kind, s)
+	e.data = data
+	raise(vm, e)
 }
 
 func TypeMismatch(vm *VM, exp, got interface{}) {
-	panic(&ErrRuntime{
-		_make_errorM(vm, "Type mismatch. Expected:", exp, "Got:", got),
-	})
+	raise(vm, _make_runtime_error(vm, "Type mismatch. Expected:", exp, "Got:",
+		got))
 }
 
 //TODO get name from VM when it saves it
@@ -85,14 +125,29 @@ func ArgumentError(vm *VM, name, spec, args interface{}) {
 	if args == nil {
 		args = "no arguments"
 	}
-	panic(&ErrRuntime{
-		_make_errorM(vm, "Illegal arguments.", name, "expected:", spec, "Got:",
-			args),
-	})
+	raise(vm, _make_runtime_error(vm, "Illegal arguments.", name, "expected:",
+		spec, "Got:", args))
+}
+
+func stepLimitExceeded(vm *VM, limit int64) {
+	raise(vm, _make_kinded_error(vm, ErrKindStepLimit, "Step limit exceeded:",
+		limit, "commands evaluated"))
+}
+
+func quotaExceeded(vm *VM, kind string, s ...interface{}) {
+	raise(vm, _make_kinded_error(vm, kind, s))
+}
+
+func cancelled(vm *VM, cause error) {
+	e := _make_kinded_error(vm, ErrKindCancelled, "Evaluation cancelled:",
+		cause.Error())
+	e.cause = cause
+	//not raised, since a hook that paused would only be cancelled again
+	panic(e)
 }
 
 func killed(vm *VM) Error {
-	return &ErrRuntime{_make_error(vm, []interface{}{"VM killed"})}
+	return _make_runtime_error(vm, "VM killed")
 }
 
 //common methods on error
@@ -102,6 +157,9 @@ func (e _error) From() uint32 {
 }
 
 func (e _error) Error() string {
+	if e.pos.Known() {
+		return e.pos.String() + ": " + e.msg
+	}
 	return e.msg
 }
 
@@ -109,6 +167,26 @@ func (e _error) Message() string {
 	return e.msg
 }
 
+//Where the error occurred. See (SrcPos).Known
+func (e _error) Position() SrcPos {
+	return e.pos
+}
+
+//Name of the source the error occurred in, "" if unknown
+func (e _error) File() string {
+	return e.pos.File
+}
+
+//Line the error occurred on, 0 if unknown
+func (e _error) Line() int {
+	return e.pos.Line
+}
+
+//Column the error occurred at, 0 if unknown
+func (e _error) Column() int {
+	return e.pos.Column
+}
+
 //syntax errors
 
 func (self *ErrSyntax) _tag() {}
@@ -157,26 +235,77 @@ func (self *ErrRuntime) Copy() Word {
 	return self
 }
 
+//the data is copied, errors are otherwise immutable
 func (self *ErrRuntime) DeepCopy() Word {
-	return self
+	if self.data == nil {
+		return self
+	}
+	e := *self
+	e.data = self.data.DeepCopy().(*Dict)
+	return &e
 }
 
 func (self *ErrRuntime) Type() Symbol {
 	return interns("*RUNTIME-ERROR*")
 }
 
+//What sort of runtime error this is. Errors without a more specific kind are
+//of kind ErrKindRuntime.
+func (self *ErrRuntime) Kind() string {
+	return self.kind
+}
+
+//The kind of e, its Kind if it is a runtime error and ErrKindSyntax if it is
+//a syntax error
+func ErrorKind(e Error) string {
+	if r, ok := e.(*ErrRuntime); ok {
+		return r.kind
+	}
+	return ErrKindSyntax
+}
+
+//The data the error was raised with by KindedError, nil if none
+func (self *ErrRuntime) Data() *Dict {
+	return self.data
+}
+
+//The Go error that caused this one, if any, such as context.Canceled for an
+//error of kind ErrKindCancelled
+func (self *ErrRuntime) Unwrap() error {
+	return self.cause
+}
+
+//The commands that were being evaluated when the error was raised, innermost
+//first. Empty if the error was not raised by a running VM.
+func (self *ErrRuntime) Frames() []Frame {
+	return self.frames
+}
+
 //Implementation details
 
 func _make_errorM(vm *VM, s ...interface{}) _error {
 	return _make_error(vm, s)
 }
 
+func _make_runtime_error(vm *VM, s ...interface{}) *ErrRuntime {
+	return _make_kinded_error(vm, ErrKindRuntime, s)
+}
+
+func _make_kinded_error(vm *VM, kind string, s ...interface{}) *ErrRuntime {
+	var frames []Frame
+	if vm != nil {
+		frames = vm._frames_snapshot()
+	}
+	return &ErrRuntime{_make_error(vm, s), kind, frames, nil, nil}
+}
+
 func _make_error(vm *VM, s []interface{}) _error {
 	var id uint32
+	var pos SrcPos
 	if vm != nil {
-		id = vm.ProcID()
+		id, pos = vm.ProcID(), vm.pos
 	}
-	return _error{id, _format(s)}
+	return _error{id, _format(s), pos}
 }
 
 func _format(all ...interface{}) string {
@@ -0,0 +1,40 @@
+package gelo_test
+
+import "testing"
+
+func TestEvalCompiled(t *testing.T) {
+	vm := _new_vm()
+	for _, c := range []struct{ src, want string }{
+		{"+ 1 [+ 2 [* 3 4]]", "15"},
+		{"set! l [List 1 2 3]; + @l", "6"},
+		{"+ @[List 1 2] 3", "6"},
+		{"set! n x; set! x 5; + $[value $n] 1", "6"},
+		{"set! x 5; + ${x} 1", "6"},
+		{"llength [List [List a b] {c d}]", "2"},
+		//a quote is compiled once but reads its variables each time
+		{"set! q { + $x 1 }; set! x 1; set! a [$q]; set! x 2; + $a [$q]",
+			"5"},
+	} {
+		if got := _eval(t, vm, c.src); got != c.want {
+			t.Errorf("%s = %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+//an error raised part of the way through building a command must not leave
+//its words on the stack of the VM
+func TestEvalAfterError(t *testing.T) {
+	vm := _new_vm()
+	for i := 0; i < 3; i++ {
+		_eval_err(t, vm, "+ 1 [+ 2 [* 3 x]]", "runtime-error")
+		_eval_err(t, vm, "set! f { + 1 [g] }; set! g { + a 1 }; f",
+			"runtime-error")
+		if got := _eval(t, vm, "+ 1 [+ 2 3]"); got != "6" {
+			t.Fatalf("+ 1 [+ 2 3] after an error = %s", got)
+		}
+	}
+	src := "List a [try { + 1 [+ 2 x] } catch e { value caught }] b"
+	if got := _eval(t, vm, src); got != "{a caught b}" {
+		t.Errorf("%s = %s", src, got)
+	}
+}
@@ -0,0 +1,131 @@
+package gelo
+
+import (
+	"bytes"
+	"time"
+)
+
+//The VM keeps a lightweight stack of the commands it is currently evaluating
+//so that runtime errors can say how they came to be. A command in tail
+//position replaces the frame of the quote that called it, so the stack does
+//not grow in loops written with tail recursion.
+
+const _frame_summary_len = 60
+
+//A Frame describes one command that was being evaluated
+type Frame struct {
+	Name    string //the command as it was written, abbreviated if long
+	Invoked Word   //the Quote or Alien invoked, nil if it never got that far
+	Args    string //the rewritten arguments, abbreviated if long
+	Pos     SrcPos
+}
+
+func (f Frame) String() string {
+	buf := newBuf(0)
+	if f.Pos.Known() {
+		buf.WriteString(f.Pos.String())
+		buf.WriteString(": ")
+	}
+	buf.WriteString(f.Name)
+	if len(f.Args) != 0 {
+		buf.WriteString(" ")
+		buf.WriteString(f.Args)
+	}
+	if f.Invoked != nil {
+		buf.WriteString(" (")
+		buf.Write(f.Invoked.Type().Bytes())
+		buf.WriteString(")")
+	}
+	return buf.String()
+}
+
+type _frame struct {
+	name    Word
+	invoked Word
+	args    *List
+	pos     SrcPos
+	start   time.Time //when it was invoked, set only when tracing
+}
+
+//on one line, abbreviated if long
+func _summarize(b []byte) string {
+	b = bytes.Join(bytes.Fields(b), []byte(" "))
+	if len(b) > _frame_summary_len {
+		return string(b[:_frame_summary_len-3]) + "..."
+	}
+	return string(b)
+}
+
+func (f *_frame) export() Frame {
+	var args string
+	if f.args != nil {
+		args = _summarize(_format1(f.args))
+	}
+	return Frame{_summarize(_format1(f.name)), f.invoked, args, f.pos}
+}
+
+//line is a rewritten command
+func (vm *VM) _push_frame(line *List) {
+	if line == nil {
+		vm.frames = append(vm.frames, _frame{Null, nil, nil, vm.pos, time.Time{}})
+	} else {
+		vm.frames = append(vm.frames, _frame{line.Value, nil, line.Next, vm.pos,
+			time.Time{}})
+	}
+	if vm.prof != nil {
+		vm.prof._push(vm.frames[len(vm.frames)-1].name, vm.pos)
+	}
+}
+
+func (vm *VM) _pop_frame() {
+	if n := len(vm.frames); n != 0 {
+		if vm.prof != nil {
+			vm.prof._pop_frame(n)
+		}
+		vm.frames[n-1] = _frame{}
+		vm.frames = vm.frames[:n-1]
+	}
+}
+
+//the top frame made a tail call so it takes the place of its caller
+func (vm *VM) _tail_frame() {
+	n := len(vm.frames)
+	if n < 2 {
+		return
+	}
+	if vm.prof != nil {
+		vm.prof._tail_frame(n)
+	}
+	vm.frames[n-2] = vm.frames[n-1]
+	vm.frames[n-1] = _frame{}
+	vm.frames = vm.frames[:n-1]
+}
+
+func (vm *VM) _frame_invoked(w Word) {
+	if n := len(vm.frames); n != 0 {
+		vm.frames[n-1].invoked = w
+	}
+}
+
+//used when recovering from a panic, which leaves the frames of everything
+//that was unwound on the stack
+func (vm *VM) _unwind_frames(depth int) {
+	for len(vm.frames) > depth {
+		vm._pop_frame()
+	}
+}
+
+//innermost first
+func (vm *VM) _frames_snapshot() []Frame {
+	out := make([]Frame, len(vm.frames))
+	for i := range vm.frames {
+		out[len(out)-1-i] = vm.frames[i].export()
+	}
+	return out
+}
+
+//Returns the commands vm is currently evaluating, innermost first.
+//Only call from the goroutine running vm, such as from an Alien.
+func (vm *VM) Frames() []Frame {
+	return vm._frames_snapshot()
+}
@@ -0,0 +1,61 @@
+package gelo_test
+
+import (
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+func _frames(t *testing.T, src string) []gelo.Frame {
+	t.Helper()
+	_, err := _new_vm().Run("f.gel", strings.NewReader(src), nil)
+	r, ok := err.(*gelo.ErrRuntime)
+	if !ok {
+		t.Fatalf("expected a runtime error, got %v", err)
+	}
+	return r.Frames()
+}
+
+func TestErrorFrames(t *testing.T) {
+	frames := _frames(t, `set! h {
+	+ 1 x
+}
+set! f { h; value 1 }
+f
+value 2
+`)
+	want := []struct{ name, args, pos string }{
+		{"+", "1 x", "f.gel:2:2"},
+		{"h", "", "f.gel:4:10"},
+		{"f", "", "f.gel:5:1"},
+		{"<program>", "", "f.gel:1:1"},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("frames %v, want %v", frames, want)
+	}
+	for i, w := range want {
+		f := frames[i]
+		if f.Name != w.name || f.Args != w.args || f.Pos.String() != w.pos {
+			t.Errorf("frame %d is %v, want %v", i, f, w)
+		}
+	}
+	if _, ok := frames[0].Invoked.(gelo.Alien); !ok {
+		t.Errorf("+ invoked %T", frames[0].Invoked)
+	}
+	if _, ok := frames[1].Invoked.(gelo.Quote); !ok {
+		t.Errorf("h invoked %T", frames[1].Invoked)
+	}
+}
+
+//a quote invoked in tail position replaces the frame of its caller
+func TestErrorFramesTail(t *testing.T) {
+	frames := _frames(t, "set! h { + 1 x }\nset! g { h }\nset! f { g }\nf\n")
+	var names []string
+	for _, f := range frames {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, " "); got != "+ h" {
+		t.Errorf("frames %s, want + h", got)
+	}
+}
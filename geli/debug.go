@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"code.google.com/p/gelo"
+	"sort"
+	"strings"
+)
+
+//the debugger is a hook on the interpreter's VM that, when it stops, runs a
+//smaller interpreter in the namespace of the command it stopped at until told
+//to continue
+
+const (
+	dbg_run  = iota //stop only at breakpoints
+	dbg_step        //stop at the next command
+	dbg_next        //stop at the next command no deeper than depth
+)
+
+type debugger struct {
+	gelo.NopHook
+	vm      *gelo.VM
+	breaks  map[string]bool
+	watches []string
+	mode    int
+	depth   int
+	frames  []gelo.Frame //where we are stopped
+	stopped bool         //in the debug interpreter
+	resume  bool         //a metacommand asked to leave the debug interpreter
+}
+
+var dbg = &debugger{breaks: make(map[string]bool)}
+
+func (d *debugger) Before(vm *gelo.VM, cmd *gelo.List) gelo.HookAction {
+	//VMs spawned by go run in other goroutines, leave them alone
+	if vm != d.vm || d.stopped {
+		return gelo.HookContinue
+	}
+	hit := d.breaks[cmd.Value.Ser().String()]
+	switch d.mode {
+	case dbg_run:
+		if !hit {
+			return gelo.HookContinue
+		}
+	case dbg_next:
+		if !hit && len(vm.Frames()) > d.depth {
+			return gelo.HookContinue
+		}
+	}
+	d.repl(vm)
+	return gelo.HookContinue
+}
+
+func (d *debugger) show_watches(vm *gelo.VM) {
+	for _, name := range d.watches {
+		if w, ok := vm.Ns.Lookup(gelo.StrToSym(name)); ok {
+			fmt.Println("\t"+name, "=", w.Ser())
+		} else {
+			fmt.Println("\t"+name, "is undefined")
+		}
+	}
+}
+
+func (d *debugger) repl(vm *gelo.VM) {
+	d.frames = vm.Frames()
+	d.stopped, d.resume = true, false
+	defer func() {
+		d.stopped, d.frames = false, nil
+	}()
+	if len(d.frames) != 0 {
+		fmt.Println("Stopped at", d.frames[0])
+	}
+	d.show_watches(vm)
+	llines := NewReadline()
+	for !d.resume {
+		read_lines(llines, "(debug) >> ")
+		for _, lline := range llines.lines {
+			ret, err := vm.API.EvalSource("", strings.NewReader(lline), nil)
+			if err != nil {
+				fmt.Println("Failed with:", err.Error())
+				show_frames(err)
+			} else if r := ret.Ser().String(); len(r) != 0 {
+				fmt.Println("=> ", r)
+			}
+			metainvoke = false
+			if d.resume {
+				break
+			}
+		}
+		llines.Reset()
+		if to_exit {
+			//nothing left to read, let the program run to completion
+			d.mode = dbg_run
+			return
+		}
+	}
+}
+
+//debugger metacommands
+
+func not_stopped() gelo.Word {
+	fmt.Println("Not stopped in the debugger")
+	return gelo.Null
+}
+
+func set_break(_ *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	switch {
+	case ac == 0:
+		var names []string
+		for name := range dbg.breaks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println("\t" + name)
+		}
+	case ac == 1:
+		dbg.breaks[args.Value.Ser().String()] = true
+	case ac == 2 && args.Next.Value.Ser().String() == "off":
+		delete(dbg.breaks, args.Value.Ser().String())
+	default:
+		return metahelp("break")
+	}
+	return gelo.Null
+}
+
+func step(_ *gelo.VM, _ *gelo.List, ac uint) gelo.Word {
+	if ac != 0 {
+		return metahelp("step")
+	}
+	if !dbg.stopped {
+		return not_stopped()
+	}
+	dbg.mode, dbg.resume = dbg_step, true
+	return gelo.Null
+}
+
+func next(_ *gelo.VM, _ *gelo.List, ac uint) gelo.Word {
+	if ac != 0 {
+		return metahelp("next")
+	}
+	if !dbg.stopped {
+		return not_stopped()
+	}
+	dbg.mode, dbg.depth, dbg.resume = dbg_next, len(dbg.frames), true
+	return gelo.Null
+}
+
+func cont(_ *gelo.VM, _ *gelo.List, ac uint) gelo.Word {
+	if ac != 0 {
+		return metahelp("continue")
+	}
+	if !dbg.stopped {
+		return not_stopped()
+	}
+	dbg.mode, dbg.resume = dbg_run, true
+	return gelo.Null
+}
+
+func locals(vm *gelo.VM, _ *gelo.List, ac uint) gelo.Word {
+	if ac != 0 {
+		return metahelp("locals")
+	}
+	m := vm.Ns.Locals(0).Map()
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println("\t"+name, "=", _foreshorten(m[name].Ser().String()))
+	}
+	return gelo.Null
+}
+
+func where(_ *gelo.VM, _ *gelo.List, ac uint) gelo.Word {
+	if ac != 0 {
+		return metahelp("where")
+	}
+	if !dbg.stopped {
+		return not_stopped()
+	}
+	for i, frame := range dbg.frames {
+		fmt.Println(i, "->", frame)
+	}
+	return gelo.Null
+}
+
+func watch(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+	switch {
+	case ac == 0:
+		dbg.show_watches(vm)
+	case ac == 1:
+		name := args.Value.Ser().String()
+		for _, w := range dbg.watches {
+			if w == name {
+				return gelo.Null
+			}
+		}
+		dbg.watches = append(dbg.watches, name)
+	case ac == 2 && args.Next.Value.Ser().String() == "off":
+		name := args.Value.Ser().String()
+		for i, w := range dbg.watches {
+			if w == name {
+				dbg.watches = append(dbg.watches[:i], dbg.watches[i+1:]...)
+				break
+			}
+		}
+	default:
+		return metahelp("watch")
+	}
+	return gelo.Null
+}
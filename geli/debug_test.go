@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+	"code.google.com/p/gelo/commands"
+)
+
+//runs program with a break on each of breaks, reading the commands of the
+//debugger from input, and returns the result and what was printed
+func _debug(t *testing.T, program, input string, breaks ...string) (string,
+	string) {
+	t.Helper()
+	vm := gelo.NewVM(gelo.NewChan())
+	defer vm.Destroy()
+	vm.Register("$", Dollar)
+	vm.RegisterBundle(gelo.Core)
+	vm.RegisterBundles(commands.All)
+	dbg = &debugger{vm: vm, breaks: make(map[string]bool)}
+	for _, b := range breaks {
+		dbg.breaks[b] = true
+	}
+	vm.SetHook(dbg)
+	stdin, to_exit = bufio.NewReader(strings.NewReader(input)), false
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, out := os.Stdout, make(chan string)
+	os.Stdout = w
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	ret, gerr := vm.Run("test.gel", strings.NewReader(program), nil)
+	os.Stdout = stdout
+	w.Close()
+	printed := <-out
+	if gerr != nil {
+		t.Fatalf("%s: %v\n%s", program, gerr, printed)
+	}
+	return ret.Ser().String(), printed
+}
+
+func TestDebugBreak(t *testing.T) {
+	//the debugger evaluates in the namespace of the program
+	ret, out := _debug(t, "set! x 1\n+ 1 2\nvalue $x",
+		"set! x 5\n$$ continue\n", "+")
+	if ret != "5" {
+		t.Errorf("the program returned %s", ret)
+	}
+	if strings.Count(out, "Stopped at") != 1 ||
+		!strings.Contains(out, "test.gel:2:1: + 1 2") {
+		t.Errorf("printed:\n%s", out)
+	}
+}
+
+func TestDebugStepWatch(t *testing.T) {
+	_, out := _debug(t, "set! x 1\n+ 1 2\nset! z 0\n+ 3 4\n",
+		"$$ watch x\n$$ step\n$$ continue\n", "+")
+	//step stops at set! z, continue at the next break
+	for _, want := range []string{"+ 1 2", "set! z 0", "x = 1", "+ 3 4"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printed no %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "Stopped at"); n != 3 {
+		t.Errorf("stopped %d times:\n%s", n, out)
+	}
+}
+
+func TestDebugNext(t *testing.T) {
+	//next steps over the commands f evaluates
+	_, out := _debug(t, "set! f { + 1 1; + 2 2 }\nf\nset! z 0\n",
+		"$$ next\n$$ continue\n", "f")
+	if strings.Contains(out, "+ 1 1") || !strings.Contains(out, "set! z 0") {
+		t.Errorf("printed:\n%s", out)
+	}
+}
+
+func TestDebugNotStopped(t *testing.T) {
+	_, out := _debug(t, "$$ step; $$ where; $$ continue", "")
+	if n := strings.Count(out, "Not stopped"); n != 3 {
+		t.Errorf("printed:\n%s", out)
+	}
+}
@@ -29,6 +29,14 @@ func check(failmsg string, e error) {
 	}
 }
 
+func show_frames(err error) {
+	if rt, ok := err.(*gelo.ErrRuntime); ok {
+		for _, frame := range rt.Frames() {
+			fmt.Println("\tin", frame)
+		}
+	}
+}
+
 //interpreter metacommands
 
 func exit(_ *gelo.VM, _ *gelo.List, ac uint) gelo.Word {
@@ -50,8 +58,12 @@ func run(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
 		return gelo.StrToSym(
 			"Could not open file " + fname + "\n" + err.Error())
 	}
-	ret, err := vm.Run(file, args.Next)
+	//a breakpoint is only good for one run
+	defer func() { dbg.mode = dbg_run }()
+	//we are already running so we evaluate the file in place
+	ret, err := vm.API.EvalSource(fname, file, args.Next)
 	if err != nil {
+		show_frames(err)
 		//XXX unclear why this type assertion is necessary as
 		//gelo.Error satisfies os.Error and gelo.Word yet without the assertion
 		//the comppiler complains that:
@@ -389,6 +401,34 @@ func init() {
 		"see i ['to j]?\n\tDisplay in full lines i to j from history or just line i if j is unspecified",
 		see,
 	}
+	dollar_map["break"] = command{
+		"break command-name? 'off?\n\tStop in the debugger before command-name is invoked or, with off, stop stopping there. Lists breakpoints if command-name is unspecified",
+		set_break,
+	}
+	dollar_map["step"] = command{
+		"step\n\tWhen stopped in the debugger, continue to the next command",
+		step,
+	}
+	dollar_map["next"] = command{
+		"next\n\tWhen stopped in the debugger, continue to the next command, stepping over any commands invoked by this one",
+		next,
+	}
+	dollar_map["continue"] = command{
+		"continue\n\tWhen stopped in the debugger, continue to the next breakpoint",
+		cont,
+	}
+	dollar_map["locals"] = command{
+		"locals\n\tDisplay the variables of the current namespace",
+		locals,
+	}
+	dollar_map["where"] = command{
+		"where\n\tWhen stopped in the debugger, display the commands being evaluated, innermost first",
+		where,
+	}
+	dollar_map["watch"] = command{
+		"watch variable? 'off?\n\tDisplay the value of variable whenever the debugger stops or, with off, stop displaying it. Displays all watched variables if variable is unspecified",
+		watch,
+	}
 	dollar_map["trace"] = command{
 		"trace ['on|'off] 'runtime? 'parser? 'alien? 'system?\n\tTurn on or off a set of traces. If no traces are specified, the default is all of them. Due to limitation in the Argument parser traces must be specified in the same order as listed.",
 		trace,
@@ -535,8 +575,30 @@ func (r *Readline) Read(p []byte) (n int, _ error) {
 	return
 }
 
+//grab one (or more if ; is used) logical lines from stdin
+func read_lines(llines *Readline, prompt string) {
+	first := true
+	for {
+		if to_exit {
+			break
+		}
+		if first {
+			fmt.Print(prompt)
+			first = false
+		} else {
+			fmt.Print(".. ")
+		}
+		pline, err := stdin.ReadSlice('\n')
+		to_exit = err != nil
+		llines.Read(pline)
+		if llines.IsComplete() {
+			break
+		}
+	}
+}
+
 func play(vm *gelo.VM, line string) {
-	if ret, err := vm.Run(strings.NewReader(line), nil); err == nil {
+	if ret, err := vm.Run("", strings.NewReader(line), nil); err == nil {
 		//don't bother showing ""
 		if r := ret.Ser().String(); len(r) != 0 {
 			fmt.Println("=> ", ret.Ser().String())
@@ -548,6 +610,7 @@ func play(vm *gelo.VM, line string) {
 		}
 	} else {
 		fmt.Println("Failed with:", err.Error())
+		show_frames(err)
 	}
 	metainvoke = false
 }
@@ -569,31 +632,16 @@ func main() {
 		defer prelude.Close()
 		check("Could not open prelude.gel", err)
 
-		_, err = vm.Run(prelude, nil)
+		_, err = vm.Run("prelude.gel", prelude, nil)
 		check("Could not load prelude", err)
 	}
 
+	dbg.vm = vm
+	vm.SetHook(dbg)
+
 	llines := NewReadline()
 	for {
-		first := true
-		//grab one (or more if ; is used) logical lines from stdin
-		for {
-			if to_exit {
-				break
-			}
-			if first {
-				fmt.Print(">> ")
-				first = false
-			} else {
-				fmt.Print(".. ")
-			}
-			pline, err := stdin.ReadSlice('\n')
-			to_exit = err != nil
-			llines.Read(pline)
-			if llines.IsComplete() {
-				break
-			}
-		}
+		read_lines(llines, ">> ")
 		for _, lline := range llines.lines {
 			play(vm, lline)
 		}
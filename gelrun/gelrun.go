@@ -83,15 +83,39 @@ var trace = flag.Bool("trace", false, "turn on all traces")
 var logit = flag.Bool("log", false, "log traces (does not activate traces)")
 var lit = flag.Bool("literate", false, "force reading in literate mode")
 var no_prelude = flag.Bool("no-prelude", false, "do not load prelude.gel")
+var profile = flag.String("profile", "",
+	"write a pprof profile of the program to file and a summary to stderr")
+var cover = flag.String("cover", "",
+	"write an lcov report of the lines of the program that ran to file")
 
 func check(failmsg string, e error) {
 	if e != nil {
 		fmt.Println(failmsg)
 		fmt.Println(e.Error())
+		if rt, ok := e.(*gelo.ErrRuntime); ok {
+			for _, frame := range rt.Frames() {
+				fmt.Println("\tin", frame)
+			}
+		}
 		os.Exit(1)
 	}
 }
 
+func write_profile(prof *gelo.Profile) {
+	out, err := os.Create(*profile)
+	check("Could not create profile", err)
+	defer out.Close()
+	check("Could not write profile", prof.WritePprof(out))
+	prof.WriteSummary(os.Stderr)
+}
+
+func write_coverage(cov *gelo.Coverage) {
+	out, err := os.Create(*cover)
+	check("Could not create coverage report", err)
+	defer out.Close()
+	check("Could not write coverage report", cov.WriteLcov(out))
+}
+
 func main() {
 	flag.Parse()
 
@@ -113,7 +137,7 @@ func main() {
 		defer prelude.Close()
 		check("Could not open prelude.gel", err)
 
-		_, err = vm.Run(prelude, nil)
+		_, err = vm.Run("prelude.gel", prelude, nil)
 		check("Could not load prelude", err)
 	}
 
@@ -147,7 +171,19 @@ func main() {
 		gelo.TraceOn(gelo.All_traces)
 	}
 
-	ret, err := vm.Run(reader, flag.Args()[1:])
+	if *profile != "" {
+		vm.StartProfile()
+	}
+	if *cover != "" {
+		vm.StartCoverage()
+	}
+	ret, err := vm.Run(file_name, reader, flag.Args()[1:])
+	if *profile != "" {
+		write_profile(vm.StopProfile())
+	}
+	if *cover != "" {
+		write_coverage(vm.StopCoverage())
+	}
 	check("===PROGRAM=ERROR===", err)
 	vm.API.Trace("The ultimate result of the program was", ret)
 }
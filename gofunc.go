@@ -0,0 +1,360 @@
+package gelo
+
+import (
+	"reflect"
+	"strings"
+)
+
+//An Alien made from a Go function by NewAlienFromFunc converts each argument
+//it is invoked with to the type of the matching parameter of the function:
+//
+//	ints, uints   an integral number that fits in the type
+//	floats        a number
+//	string        the serialization of any word
+//	bool          a Bool
+//	slices        a list, each item converted to the type of the elements
+//	arrays        a list of as many items as the array has elements
+//	maps          a dict, the keys of the map must be strings
+//	Words         a word of that type, lists and dicts are unserialized
+//	interface{}   the word itself
+//
+//The function may take a *VM as its first parameter, which is passed the VM
+//invoking the Alien, and may be variadic. The results are converted back to
+//words by Convert, a function returning nothing returns Null and one returning
+//more than one value returns them as a list. If the last result is an error
+//and it is not nil, it is raised as a runtime error instead, or as itself if
+//it is already an Error.
+
+var (
+	_word_type  = reflect.TypeOf((*Word)(nil)).Elem()
+	_error_type = reflect.TypeOf((*error)(nil)).Elem()
+	_vm_type    = reflect.TypeOf((*VM)(nil))
+)
+
+//converts an argument to the type of a parameter or raises an error
+type _go_arg func(vm *VM, w Word) reflect.Value
+
+//Returns an Alien that invokes fn, which must be a function, converting its
+//arguments and results as described above. The name is used in the errors
+//raised when the Alien is invoked with the wrong number of arguments.
+func NewAlienFromFunc(name string, fn interface{}) Alien {
+	f := reflect.ValueOf(fn)
+	if f.Kind() != reflect.Func || f.IsNil() {
+		programmerError(nil, "NewAlienFromFunc given", f.Kind(),
+			"instead of a function")
+	}
+	return _func_alien(name, f)
+}
+
+func _func_alien(name string, f reflect.Value) Alien {
+	t := f.Type()
+	first, pass_vm := 0, t.NumIn() != 0 && t.In(0) == _vm_type
+	if pass_vm {
+		first = 1
+	}
+	last := t.NumIn()
+	if t.IsVariadic() {
+		last--
+	}
+	spec := newBuf(0)
+	var args []_go_arg
+	for i := first; i < last; i++ {
+		conv, what := _arg_converter(t.In(i))
+		if conv == nil {
+			programmerError(nil, "Cannot convert words to", t.In(i), "for", name)
+		}
+		args = append(args, conv)
+		spec.WriteString(what)
+		spec.WriteString(" ")
+	}
+	var rest _go_arg
+	if t.IsVariadic() {
+		var what string
+		rest, what = _arg_converter(t.In(last).Elem())
+		if rest == nil {
+			programmerError(nil, "Cannot convert words to", t.In(last).Elem(),
+				"for", name)
+		}
+		spec.WriteString(what)
+		spec.WriteString("*")
+	}
+	if spec.Len() == 0 {
+		spec.WriteString("nothing")
+	}
+	specs := strings.TrimSpace(spec.String())
+	results := t.NumOut()
+	returns_err := results != 0 && t.Out(results-1) == _error_type
+	if returns_err {
+		results--
+	}
+	for i := 0; i < results; i++ {
+		if !_can_convert_result(t.Out(i)) {
+			programmerError(nil, "Cannot convert", t.Out(i), "to a word for",
+				name)
+		}
+	}
+	fixed := uint(len(args))
+	return func(vm *VM, params *List, ac uint) Word {
+		if ac < fixed || (rest == nil && ac > fixed) {
+			ArgumentError(vm, name, specs, params)
+		}
+		in := make([]reflect.Value, 0, first+int(ac))
+		if pass_vm {
+			in = append(in, reflect.ValueOf(vm))
+		}
+		for _, conv := range args {
+			in = append(in, conv(vm, params.Value))
+			params = params.Next
+		}
+		for ; params != nil; params = params.Next {
+			in = append(in, rest(vm, params.Value))
+		}
+		out := f.Call(in)
+		if returns_err {
+			if err := out[results]; !err.IsNil() {
+				switch e := err.Interface().(type) {
+				case Error:
+					raise(vm, e)
+				case error:
+					RuntimeError(vm, e.Error())
+				}
+			}
+		}
+		switch results {
+		case 0:
+			return Null
+		case 1:
+			return _from_go(out[0])
+		}
+		words := make([]Word, results)
+		for i := range words {
+			words[i] = _from_go(out[i])
+		}
+		return NewListFrom(words)
+	}
+}
+
+//Returns a converter for t and what it expects, or nil if words cannot be
+//converted to t
+func _arg_converter(t reflect.Type) (_go_arg, string) {
+	switch t {
+	case _word_type:
+		return func(_ *VM, w Word) reflect.Value {
+			return reflect.ValueOf(&w).Elem()
+		}, "word"
+	case reflect.TypeOf((*Number)(nil)):
+		return func(vm *VM, w Word) reflect.Value {
+			return reflect.ValueOf(vm.API.NumberOrElse(w))
+		}, "number"
+	case reflect.TypeOf((*Decimal)(nil)):
+		return func(vm *VM, w Word) reflect.Value {
+			return reflect.ValueOf(vm.API.DecimalOrElse(w))
+		}, "decimal"
+	case reflect.TypeOf((*List)(nil)):
+		return func(vm *VM, w Word) reflect.Value {
+			return reflect.ValueOf(vm.API.ListOrElse(w))
+		}, "list"
+	case reflect.TypeOf((*Dict)(nil)):
+		return func(vm *VM, w Word) reflect.Value {
+			return reflect.ValueOf(vm.API.DictOrElse(w))
+		}, "dict"
+	case reflect.TypeOf(True):
+		return func(vm *VM, w Word) reflect.Value {
+			return reflect.ValueOf(vm.API.BoolOrElse(w))
+		}, "bool"
+	case reflect.TypeOf((*Symbol)(nil)).Elem():
+		return func(_ *VM, w Word) reflect.Value {
+			s := w.Ser()
+			return reflect.ValueOf(&s).Elem()
+		}, "symbol"
+	case reflect.TypeOf((*Quote)(nil)).Elem():
+		return func(vm *VM, w Word) reflect.Value {
+			q := vm.API.QuoteOrElse(w)
+			return reflect.ValueOf(&q).Elem()
+		}, "quote"
+	}
+	if t.Kind() == reflect.Interface && _word_type.Implements(t) {
+		//interface{} or an interface every word satisfies
+		return func(_ *VM, w Word) reflect.Value {
+			v := reflect.New(t).Elem()
+			v.Set(reflect.ValueOf(w))
+			return v
+		}, "word"
+	}
+	if t.Implements(_word_type) {
+		what := t.String()
+		return func(vm *VM, w Word) reflect.Value {
+			v := reflect.ValueOf(w)
+			if !v.Type().AssignableTo(t) {
+				TypeMismatch(vm, what, w.Type())
+			}
+			out := reflect.New(t).Elem()
+			out.Set(v)
+			return out
+		}, what
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return func(vm *VM, w Word) reflect.Value {
+			n := vm.API.NumberOrElse(w)
+			i, ok := n.Int()
+			if !ok && !n.IsInteger() {
+				TypeMismatch(vm, "integer", w.Ser())
+			}
+			v := reflect.New(t).Elem()
+			if !ok || v.OverflowInt(i) {
+				RuntimeError(vm, "Number out of range for", t.String()+":", w)
+			}
+			v.SetInt(i)
+			return v
+		}, "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return func(vm *VM, w Word) reflect.Value {
+			u, ok, in_range := _uint(vm.API.NumberOrElse(w))
+			if !ok {
+				TypeMismatch(vm, "integer", w.Ser())
+			}
+			v := reflect.New(t).Elem()
+			if !in_range || v.OverflowUint(u) {
+				RuntimeError(vm, "Number out of range for", t.String()+":", w)
+			}
+			v.SetUint(u)
+			return v
+		}, "integer"
+	case reflect.Float32, reflect.Float64:
+		return func(vm *VM, w Word) reflect.Value {
+			v := reflect.New(t).Elem()
+			v.SetFloat(vm.API.NumberOrElse(w).Real())
+			return v
+		}, "number"
+	case reflect.String:
+		return func(_ *VM, w Word) reflect.Value {
+			v := reflect.New(t).Elem()
+			v.SetString(w.Ser().String())
+			return v
+		}, "string"
+	case reflect.Bool:
+		return func(vm *VM, w Word) reflect.Value {
+			v := reflect.New(t).Elem()
+			v.SetBool(bool(vm.API.BoolOrElse(w)))
+			return v
+		}, "bool"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return func(_ *VM, w Word) reflect.Value {
+				v := reflect.New(t).Elem()
+				v.SetBytes(dup(w.Ser().Bytes()))
+				return v
+			}, "string"
+		}
+		elem, _ := _arg_converter(t.Elem())
+		if elem == nil {
+			return nil, ""
+		}
+		return func(vm *VM, w Word) reflect.Value {
+			l := vm.API.ListOrElse(w)
+			v := reflect.MakeSlice(t, 0, l.Len())
+			for ; l != nil; l = l.Next {
+				v = reflect.Append(v, elem(vm, l.Value))
+			}
+			return v
+		}, "list"
+	case reflect.Array:
+		elem, _ := _arg_converter(t.Elem())
+		if elem == nil {
+			return nil, ""
+		}
+		return func(vm *VM, w Word) reflect.Value {
+			l := vm.API.ListOrElse(w)
+			if l.Len() != t.Len() {
+				RuntimeError(vm, "Expected a list of", t.Len(), "items. Got:",
+					l.Len())
+			}
+			v := reflect.New(t).Elem()
+			for i := 0; l != nil; l, i = l.Next, i+1 {
+				v.Index(i).Set(elem(vm, l.Value))
+			}
+			return v
+		}, "list"
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, ""
+		}
+		elem, _ := _arg_converter(t.Elem())
+		if elem == nil {
+			return nil, ""
+		}
+		return func(vm *VM, w Word) reflect.Value {
+			d := vm.API.DictOrElse(w)
+			v := reflect.MakeMapWithSize(t, len(d.rep))
+			for k, val := range d.rep {
+				v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()),
+					elem(vm, val))
+			}
+			return v
+		}, "dict"
+	}
+	return nil, ""
+}
+
+//Returns n as a uint64, ok false if it is not an integer and in_range false if
+//it is negative or too large
+func _uint(n *Number) (u uint64, ok, in_range bool) {
+	if b, exact := n.BigInt(); exact {
+		return b.Uint64(), true, b.IsUint64()
+	}
+	i, ok := n.Int()
+	if !ok {
+		//an integer too large for an int64 is out of range
+		return 0, n.IsInteger(), false
+	}
+	return uint64(i), true, i >= 0
+}
+
+func _can_convert_result(t reflect.Type) bool {
+	return _convertible(t, make(map[reflect.Type]bool))
+}
+
+//whether Convert can convert the values of type t, seen guards against types
+//such as type T []T
+func _convertible(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	if t.Implements(_word_type) || _converter(t) != nil {
+		return true
+	}
+	switch t {
+	case _time_type, _duration_type:
+		return true
+	}
+	if t.Implements(_error_type) || t.Implements(_stringer_type) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32,
+		reflect.Float64, reflect.String, reflect.Bool, reflect.Interface,
+		reflect.Struct:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return _convertible(t.Elem(), seen)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && _convertible(t.Elem(), seen)
+	}
+	return false
+}
+
+//converts a result of a function made into an Alien to a word
+func _from_go(v reflect.Value) Word {
+	//a nil *List is the empty list
+	if (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) &&
+		v.IsNil() && v.Type() != reflect.TypeOf(EmptyList) {
+		return Null
+	}
+	return Convert(v.Interface())
+}
@@ -0,0 +1,80 @@
+package gelo_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+func TestRegisterFunc(t *testing.T) {
+	vm := _new_vm()
+	vm.RegisterFunc("add", func(a int, b uint8) int { return a + int(b) })
+	vm.RegisterFunc("half", func(f float64) float64 { return f / 2 })
+	vm.RegisterFunc("cat", func(sep string, s ...string) string {
+		return strings.Join(s, sep)
+	})
+	vm.RegisterFunc("not", func(b bool) bool { return !b })
+	vm.RegisterFunc("sum", func(xs []int) (n int) {
+		for _, x := range xs {
+			n += x
+		}
+		return
+	})
+	vm.RegisterFunc("swap", func(a [2]string) string { return a[1] + a[0] })
+	vm.RegisterFunc("keys", func(m map[string]int) int { return m["a"] + m["b"] })
+	vm.RegisterFunc("two", func() (int, string) { return 1, "x" })
+	vm.RegisterFunc("none", func() {})
+	vm.RegisterFunc("type", func(vm *gelo.VM, w gelo.Word) string {
+		return w.Type().String()
+	})
+	vm.RegisterFunc("llen", func(l *gelo.List) int { return l.Len() })
+	vm.RegisterFunc("any", func(x interface{}) gelo.Word { return x.(gelo.Word) })
+	for _, c := range []struct{ src, want string }{
+		{"add 1 2", "3"},
+		{"half 3", "1.5"},
+		{"cat , a b c", "a,b,c"},
+		{"cat ,", ""},
+		{"not [= 1 2]", "true"},
+		{"sum {1 2 3}", "6"},
+		{"swap {a b}", "ba"},
+		{"keys {{a 1} {b 2}}", "3"},
+		{"two", "{1 x}"},
+		{"none", ""},
+		{"type 1", "*NUMBER*"},
+		{"llen {a b}", "2"},
+		{"any hi", "hi"},
+	} {
+		if got := _eval(t, vm, c.src); got != c.want {
+			t.Errorf("%s = %s, want %s", c.src, got, c.want)
+		}
+	}
+	for _, src := range []string{"add 1 256", "add 1.5 2", "add 1",
+		"add 1 2 3", "half x", "not 1", "sum {1 x}", "swap {a b c}"} {
+		_eval_err(t, vm, src, gelo.ErrKindRuntime)
+	}
+}
+
+func TestRegisterFuncErrors(t *testing.T) {
+	vm, inner := _new_vm(), _new_vm()
+	vm.RegisterFunc("fail", func(fail int) (int, error) {
+		if fail != 0 {
+			return 0, errors.New("failed")
+		}
+		return 1, nil
+	})
+	//an Error is raised as itself and keeps its kind
+	vm.RegisterFunc("reraise", func() error {
+		_, err := inner.Do("raise oops")
+		return err
+	})
+	if got := _eval(t, vm, "fail 0"); got != "1" {
+		t.Errorf("fail 0 = %s", got)
+	}
+	err := _eval_err(t, vm, "fail 1", gelo.ErrKindRuntime)
+	if !strings.Contains(err.Error(), "failed") {
+		t.Errorf("fail 1 raised %v", err)
+	}
+	_eval_err(t, vm, "reraise", "oops")
+}
@@ -0,0 +1,146 @@
+package gelo
+
+import "sync"
+
+//What the VM should do after a Hook returns
+type HookAction int
+
+const (
+	HookContinue HookAction = iota
+	HookPause               //block until (*VM).Resume is called
+)
+
+//A Hook is called by the VM as it evaluates, from the goroutine running the
+//VM, so it may use vm.Ns to inspect or change the namespaces and vm.Frames
+//to see how the VM got where it is. The cmd passed to each is the rewritten
+//command, its head followed by its arguments.
+//
+//A command invoked in tail position takes the place of its caller and
+//returns when it does, so After is called once for the both of them.
+//Error is called when the error is raised, before it unwinds anything.
+type Hook interface {
+	Before(vm *VM, cmd *List) HookAction
+	After(vm *VM, cmd *List, result Word) HookAction
+	Error(vm *VM, cmd *List, err Error) HookAction
+	Fork(vm *VM) HookAction
+	Unfork(vm *VM) HookAction
+}
+
+//NopHook does nothing. Embed it in a Hook to only implement the
+//callbacks of interest.
+type NopHook struct{}
+
+func (NopHook) Before(*VM, *List) HookAction       { return HookContinue }
+func (NopHook) After(*VM, *List, Word) HookAction  { return HookContinue }
+func (NopHook) Error(*VM, *List, Error) HookAction { return HookContinue }
+func (NopHook) Fork(*VM) HookAction                { return HookContinue }
+func (NopHook) Unfork(*VM) HookAction              { return HookContinue }
+
+type _pause struct {
+	mux    sync.Mutex
+	resume chan struct{} //nil unless paused
+}
+
+//Set the hook of vm and any VMs it spawns from now on. nil removes the hook.
+//Call before running vm or from its goroutine, such as from the hook itself.
+func (vm *VM) SetHook(h Hook) {
+	vm._sanity("set a hook")
+	vm.hook = h
+}
+
+//Returns the hook of vm, nil if there is none
+func (vm *VM) Hook() Hook {
+	return vm.hook
+}
+
+//Unblocks vm if a hook paused it. Safe to call from any goroutine. Returns
+//false if vm was not paused.
+func (vm *VM) Resume() bool {
+	p := vm.pause
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.resume == nil {
+		return false
+	}
+	close(p.resume)
+	p.resume = nil
+	return true
+}
+
+//Reports whether vm is blocked by a hook. Safe to call from any goroutine.
+func (vm *VM) Paused() bool {
+	p := vm.pause
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.resume != nil
+}
+
+//blocks until resumed, killed or cancelled
+func (vm *VM) _pause() {
+	p := vm.pause
+	p.mux.Lock()
+	resume := make(chan struct{})
+	p.resume = resume
+	p.mux.Unlock()
+	defer func() {
+		p.mux.Lock()
+		if p.resume == resume {
+			p.resume = nil
+		}
+		p.mux.Unlock()
+	}()
+	sys_trace(vm, "VM", vm.id, "paused")
+	select {
+	case <-resume:
+	case <-vm.kill_switch:
+		panic(kill_control_code(byte(0)))
+	case <-vm.done:
+		cancelled(vm, vm.ctx.Err())
+	}
+	sys_trace(vm, "VM", vm.id, "resumed")
+}
+
+func (vm *VM) _hook_act(a HookAction) {
+	if a == HookPause {
+		vm._pause()
+	}
+}
+
+func (vm *VM) _hook_before(cmd *List) {
+	vm._hook_act(vm.hook.Before(vm, cmd))
+}
+
+func (vm *VM) _hook_after(cmd *List, result Word) {
+	if vm._tracing(Runtime_trace) {
+		vm._trace_return(result)
+	}
+	if vm.hook != nil {
+		vm._hook_act(vm.hook.After(vm, cmd, result))
+	}
+}
+
+//called with the error about to be raised
+func (vm *VM) _hook_error(err Error) {
+	if vm == nil || vm.hook == nil {
+		return
+	}
+	var cmd *List
+	if n := len(vm.frames); n != 0 {
+		if f := vm.frames[n-1]; f.name != nil {
+			cmd = &List{f.name, f.args}
+		}
+	}
+	vm._hook_act(vm.hook.Error(vm, cmd, err))
+}
+
+func (vm *VM) _hook_fork() {
+	if vm.hook != nil {
+		vm._hook_act(vm.hook.Fork(vm))
+	}
+}
+
+func (vm *VM) _hook_unfork() {
+	if vm.hook != nil {
+		vm._hook_act(vm.hook.Unfork(vm))
+	}
+}
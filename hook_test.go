@@ -0,0 +1,140 @@
+package gelo_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"code.google.com/p/gelo"
+)
+
+type _recorder struct {
+	gelo.NopHook
+	events []string
+	pause  string //the command to pause before
+}
+
+//cmd without its braces
+func _cmd(cmd *gelo.List) string {
+	s := cmd.Ser().String()
+	return strings.Replace(s[1:len(s)-1], `"`, "", -1)
+}
+
+func (r *_recorder) Before(vm *gelo.VM, cmd *gelo.List) gelo.HookAction {
+	r.events = append(r.events, "before "+_cmd(cmd))
+	if _cmd(cmd) == r.pause {
+		return gelo.HookPause
+	}
+	return gelo.HookContinue
+}
+
+func (r *_recorder) After(vm *gelo.VM, cmd *gelo.List,
+	result gelo.Word) gelo.HookAction {
+	r.events = append(r.events, "after "+_cmd(cmd)+" = "+
+		result.Ser().String())
+	return gelo.HookContinue
+}
+
+func (r *_recorder) Error(vm *gelo.VM, cmd *gelo.List,
+	err gelo.Error) gelo.HookAction {
+	r.events = append(r.events, "error "+_cmd(cmd))
+	return gelo.HookContinue
+}
+
+func (r *_recorder) Fork(*gelo.VM) gelo.HookAction {
+	r.events = append(r.events, "fork")
+	return gelo.HookContinue
+}
+
+func (r *_recorder) Unfork(*gelo.VM) gelo.HookAction {
+	r.events = append(r.events, "unfork")
+	return gelo.HookContinue
+}
+
+func TestHook(t *testing.T) {
+	for _, c := range []struct{ src, want string }{
+		{"+ 1 [* 2 3]",
+			"before * 2 3|after * 2 3 = 6|before + 1 6|after + 1 6 = 7"},
+		//the tail call returns for f
+		{"set! f { + 1 2 }; f",
+			"before set! f  + 1 2 |after set! f  + 1 2  =  + 1 2 |" +
+				"before f|before + 1 2|after + 1 2 = 3"},
+		{"+ 1 x", "before + 1 x|error + 1 x"},
+		//spawned VMs have the hook too
+		{"safe-eval { + 1 1 }",
+			"before safe-eval  + 1 1 |before + 1 1|after + 1 1 = 2|" +
+				"after safe-eval  + 1 1  = 2"},
+	} {
+		vm, r := _new_vm(), &_recorder{}
+		vm.SetHook(r)
+		vm.Do(c.src)
+		if got := strings.Join(r.events, "|"); got != c.want {
+			t.Errorf("%s hooked as\n\t%s\nwant\n\t%s", c.src, got, c.want)
+		}
+	}
+}
+
+func TestHookFork(t *testing.T) {
+	vm, r := _new_vm(), &_recorder{}
+	vm.SetHook(r)
+	_eval(t, vm, "ns fork; ns unfork")
+	var forks []string
+	for _, e := range r.events {
+		if e == "fork" || e == "unfork" {
+			forks = append(forks, e)
+		}
+	}
+	if got := strings.Join(forks, " "); got != "fork unfork" {
+		t.Errorf("forks hooked as %s", got)
+	}
+	vm.SetHook(nil)
+	if vm.Hook() != nil {
+		t.Error("the hook was not removed")
+	}
+}
+
+func TestHookPause(t *testing.T) {
+	vm, r := _new_vm(), &_recorder{pause: "+ 1 2"}
+	vm.SetHook(r)
+	if vm.Resume() {
+		t.Error("resumed a VM that was not paused")
+	}
+	done := make(chan string)
+	go func() {
+		ret, err := vm.Do("+ 1 2")
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		done <- ret.Ser().String()
+	}()
+	for start := time.Now(); !vm.Paused(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the hook never paused the VM")
+		}
+	}
+	select {
+	case got := <-done:
+		t.Fatalf("paused VM returned %s", got)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if !vm.Resume() {
+		t.Error("Resume did not find the VM paused")
+	}
+	if got := <-done; got != "3" {
+		t.Errorf("+ 1 2 = %s after resuming", got)
+	}
+}
+
+func TestHookPauseCancelled(t *testing.T) {
+	vm := _new_vm()
+	vm.SetHook(&_recorder{pause: "+ 1 2"})
+	ctx, cancel := context.WithTimeout(context.Background(),
+		20*time.Millisecond)
+	defer cancel()
+	_, err := vm.DoContext(ctx, "+ 1 2")
+	if gelo.ErrorKind(err) != gelo.ErrKindCancelled {
+		t.Errorf("a pause past the deadline gave %v", err)
+	}
+}
@@ -1,76 +1,29 @@
 package gelo
 
-func (vm *VM) _deref(item *sNode) (ret Word) {
-	switch item.tag {
-	case synClause:
-		cmd_ret := vm._eval_line(item.val.(*sNode))
-		switch t := cmd_ret.(type) {
-		default:
-			TypeMismatch(vm, "symbol or quote", cmd_ret.Type())
-		case Quote:
-			ret = t.Ser()
-		case Symbol:
-			ret = t
-		}
-	case synQuote:
-		ret = item.val.(Quote).Ser()
-	case synLiteral:
-		ret = item.val.(Word)
-	default:
-		systemError(vm, "invalid node type dereferenced--parser incorrect",
-			item)
+//called once for every command evaluated
+func (vm *VM) _tick() {
+	//before we continue, see if anyone wants us to stop
+	vm._poll()
+	if vm.budget != nil {
+		vm._step()
 	}
-	ret = vm.Ns.LookupOrElse(ret)
-	run_trace("derefed", item, "=>", ret)
-	return
 }
 
-func (vm *VM) rewrite(c *sNode) (*List, uint) {
-	var head, tail *List
-	ac := uint(0)
-	fill := func(item Word) {
-		if head != nil {
-			ac++
-			tail.Next = &List{item, nil}
-			tail = tail.Next
-		} else {
-			//initial word is the command, so we don't inc ac
-			head = &List{item, nil}
-			tail = head
-		}
+//call is true if c is the code of a quote invoked by name, and so a boundary
+//for return, rather than a quote an Alien returned to be invoked in its place
+func (vm *VM) peval(line *List, ac uint) (ret Word, c *_code, args *List,
+	call bool) {
+	vm._tick()
+	if line == nil {
+		//everything spliced away, which is as good as a Noop
+		return Null, nil, nil, false
 	}
-	for cmd := c; cmd != nil; cmd = cmd.next {
-		switch cmd.tag {
-		case synLiteral, synQuote:
-			fill(cmd.val.(Word))
-		case synIndirect:
-			fill(vm._deref(cmd.val.(*sNode)))
-		case synClause:
-			fill(vm._eval_line(cmd.val.(*sNode)))
-		case synSplice:
-			c := cmd.val.(*sNode)
-			var splice Word
-			if c.tag == synClause {
-				//cannot use dereference because we expect the result to be
-				//a list not a symbol
-				splice = vm._eval_line(c.val.(*sNode))
-			} else {
-				splice = vm._deref(c)
-			}
-			s, ok := splice.(*List)
-			if !ok {
-				RuntimeError(vm, "Attempted to splice nonlist")
-			}
-			for ; s != nil; s = s.Next {
-				fill(s.Value)
-			}
-		}
+	if vm._tracing(Runtime_trace) {
+		vm._trace_invoke()
+	}
+	if vm.hook != nil {
+		vm._hook_before(line)
 	}
-	run_trace("rewrote", c, "to", head)
-	return head, ac
-}
-
-func (vm *VM) peval(line *List, ac uint) (ret Word, c *command, args *List) {
 	ret, args = line.Value, line.Next
 	if q, ok := ret.(Quote); ok {
 		//if the head is a quote we optimistically mark it invokable
@@ -78,13 +31,14 @@ func (vm *VM) peval(line *List, ac uint) (ret Word, c *command, args *List) {
 	} else if _, ok = ret.(Alien); !ok {
 		//Not a quote or alien, we attempt to dereference the serialization
 		//of the command and had better get a quote or alien (or defer)
-		run_trace("evaluating named command", ret)
+		run_trace(vm, "evaluating named command", ret)
 		switch cmd := vm.Ns.LookupOrElse(ret).(type) {
 		default:
 			TypeMismatch(vm, "invokable", ret.Type())
 		case Quote:
 			//dereferenced a quote so we assume that the value is invokable
 			ret = cmd.unprotect()
+			call = true
 		case Alien:
 			ret = cmd
 		case *defert:
@@ -98,10 +52,12 @@ func (vm *VM) peval(line *List, ac uint) (ret Word, c *command, args *List) {
 		return
 	}
 
+	vm._frame_invoked(ret)
+
 	//either an anonymous alien (like the result of something like the compose
 	// command in gelo/commands/combinators.go)
 	if gocmd, ok := ret.(Alien); ok {
-		run_trace("invoking alien")
+		run_trace(vm, "invoking alien")
 		//it is up to gocmd to mark the quote invokable if it wishes
 		ret = gocmd(vm, args, ac)
 		args = nil
@@ -109,8 +65,11 @@ func (vm *VM) peval(line *List, ac uint) (ret Word, c *command, args *List) {
 
 	//either the head or the result of one of the above
 	if q, ok := ret.(*quote); ok {
-		run_trace("invoking quote")
-		c, ok = q.fcode()
+		run_trace(vm, "invoking quote")
+		if vm.cover != nil {
+			vm.cover._quote(q)
+		}
+		c, ok = q.fbytecode()
 		ret = nil
 		if !ok {
 			//attempted to invoke a literal, nonempty quote,
@@ -122,75 +81,180 @@ func (vm *VM) peval(line *List, ac uint) (ret Word, c *command, args *List) {
 	return
 }
 
-func (vm *VM) _eval_line(line *sNode) Word {
-	w, c, args := vm.peval(vm.rewrite(line))
-	//if we are only evaluating a single line that means we're in a clause
-	if _, ok := w.(*defert); ok {
-		RuntimeError(vm, "defer commands must not be in a clause")
+var argument_sym = interns("arguments")
+
+//A defer registered by a quote, run when eval is done with the quote and
+//everything it tail called
+type _deferred struct {
+	cmd  *List
+	pos  SrcPos
+	ns   *namespace
+	args *List //the arguments of the quote that registered it
+}
+
+//the value arguments had in a namespace before eval set it
+type _saved_args struct {
+	ns    *namespace
+	args  Word
+	there bool
+}
+
+//What eval must undo once it is done with a quote and everything it tail
+//called. Tail calls reuse the scope so they run in constant space.
+type _scope struct {
+	base  int //the defers above base in vm.defers are ours
+	saved []_saved_args
+}
+
+func (s *_scope) _save_args(ns *namespace) {
+	for i := range s.saved {
+		if s.saved[i].ns == ns {
+			//the first value we saw is the one to restore
+			return
+		}
+	}
+	args, there := ns.get(argument_sym)
+	s.saved = append(s.saved, _saved_args{ns, args, there})
+}
+
+func (vm *VM) _exit_scope(s *_scope) {
+	pos := vm.pos
+	defer func() {
+		for i := len(s.saved) - 1; i >= 0; i-- {
+			if a := s.saved[i]; a.there {
+				a.ns.set(argument_sym, a.args)
+			} else {
+				a.ns.del(argument_sym)
+			}
+		}
+		vm.pos = pos
+	}()
+	vm._run_defers(s.base)
+}
+
+//runs the defers above base, last first. Like Go's defers, the rest still
+//run if one of them panics.
+func (vm *VM) _run_defers(base int) {
+	n := len(vm.defers) - 1
+	if n < base {
+		return
+	}
+	d := vm.defers[n]
+	vm.defers[n] = _deferred{}
+	vm.defers = vm.defers[:n]
+	defer vm._run_defers(base)
+	if d.pos.Known() {
+		vm.pos = d.pos
 	}
+	d.ns.set(argument_sym, d.args)
+	run_trace(vm, "invoking defer:", d.cmd)
+	vm._push_frame(d.cmd)
+	w, c, args, _ := vm.peval(d.cmd, uint(d.cmd.Len()-1))
 	if c != nil {
-		return vm.eval(c, args)
+		w = vm.eval(c, args, true)
 	}
-	return w
+	vm._hook_after(d.cmd, w)
+	vm._pop_frame()
+	run_trace(vm, "defer handler invoked")
 }
 
-var argument_sym = interns("arguments")
-
-func (vm *VM) eval(script *command, arguments *List) (ret Word) {
+//call is true if script is the code of a quote invoked as a command, which
+//stops a return from anything it invokes, and false for a quote an Alien
+//invoked in its own place, such as the branch of an if, which a return passes
+//through. A tail call to a quote invoked by name makes the rest a call.
+func (vm *VM) eval(script *_code, arguments *List, call bool) (ret Word) {
 	if script == nil {
 		//handle Noop
 		return Null
 	}
-	var c *command
+	var c *_code
+	var args *List
+	var named bool
+	//restored on the way out so errors in the caller are reported at the
+	//caller's position. If we are unwinding from an error we want the
+	//position to stay where the error occurred.
+	pos := vm.pos
+	scope := _scope{base: len(vm.defers)}
+	depth := vm._depth()
+	//a quote invoked as a command is not in the loops of its caller, so that
+	//break and continue stop at it as return does
+	loops := vm.loops
+	if call {
+		vm.loops = 0
+	}
+	defer func() {
+		vm.loops = loops
+		if !call {
+			return
+		}
+		if x := recover(); x != nil {
+			r, ok := x.(return_control_code)
+			if !ok {
+				panic(x)
+			}
+			vm._unwind(depth)
+			vm.pos = pos
+			ret = r.value
+			run_trace(vm, "returned", ret)
+		}
+	}()
+	//deferred after the recover above so that it also stops a return in one
+	//of our defers, which returns from this quote like any other
+	defer vm._exit_scope(&scope)
 	for script != nil {
 		//store arguments
-		//TODO use something else so we can tail recurse
-		//without blowing the defer stack
 		ns := vm.cns
-		oldargs, there := ns.get(argument_sym)
+		scope._save_args(ns)
 		ns.set(argument_sym, arguments)
-		if there {
-			defer ns.set(argument_sym, oldargs)
-		} else {
-			defer ns.del(argument_sym)
-		}
-		run_trace("evaluation started")
-		if script.next != nil { //allows 1 liners
-			for ; script.next != nil; script = script.next {
-				ret, c, arguments = vm.peval(vm.rewrite(script.cmd))
-				if _, ok := ret.(*defert); ok {
-					//attach a defer handler
-					if arguments == nil {
-						ArgumentError(vm, "defer", "No command to defer", "")
-					}
-					defer func(arguments *List) {
-						run_trace("invoking defer:", arguments)
-						ac := uint(arguments.Len())
-						_, c, arguments = vm.peval(arguments, ac)
-						if c != nil {
-							vm.eval(c, arguments)
-						}
-						run_trace("defer handler invoked")
-					}(arguments)
-					run_trace("attached defer handler:", arguments)
-				} else if c != nil {
+		run_trace(vm, "evaluation started")
+		instrs := script.instrs
+		pc := vm._build(instrs, 0)
+		for ; instrs[pc].op != op_tail; pc = vm._build(instrs, pc+1) {
+			line, ac, lpos := vm._collect(&instrs[pc])
+			vm._push_frame(line)
+			if instrs[pc].op == op_defer && vm._is_defer(line) {
+				vm._tick()
+				ret, c, args = BI_defer, nil, line.Next
+			} else {
+				ret, c, args, named = vm.peval(line, ac)
+			}
+			if _, ok := ret.(*defert); ok {
+				//attach a defer handler
+				if args == nil {
+					ArgumentError(vm, "defer", "No command to defer", "")
+				}
+				vm.defers = append(vm.defers,
+					_deferred{args, vm.pos, ns, arguments})
+				run_trace(vm, "attached defer handler:", args)
+			} else {
+				if c != nil {
 					//not a defer, but got code
-					vm.eval(c, arguments)
+					ret = vm.eval(c, args, named)
 				}
+				vm._hook_after(line, ret)
 			}
+			vm._pop_frame()
+			vm.pos = lpos
 		}
 		//tail call (or 1 liner)
-		ret, script, arguments = vm.peval(vm.rewrite(script.cmd))
+		line, ac, _ := vm._collect(&instrs[pc])
+		vm._push_frame(line)
+		ret, script, arguments, named = vm.peval(line, ac)
 		if _, ok := ret.(*defert); ok {
 			RuntimeError(vm, "defer call cannot be in tail position")
 		}
-		//before we continue, see if anyone wants to kill us
-		select {
-		case <-vm.kill_switch:
-			panic(kill_control_code(byte(0)))
-		default:
+		if script != nil {
+			if named {
+				call = true
+				vm.loops = 0
+			}
+			vm._tail_frame()
+		} else {
+			vm._hook_after(line, ret)
+			vm._pop_frame()
 		}
 	}
-	run_trace("evaluation became", ret)
+	vm.pos = pos
+	run_trace(vm, "evaluation became", ret)
 	return ret
 }
@@ -1,5 +1,7 @@
 package gelo
 
+import "reflect"
+
 var EmptyList *List = nil
 
 func NewList(s ...Word) *List {
@@ -19,7 +21,21 @@ func NewListFrom(s []Word) *List {
 	return head
 }
 
-func NewListFromGo(s []interface{}) *List {
+//Converts each item of s, a slice or array of any type, as Convert does
+func NewListFromGo(s interface{}) *List {
+	items, ok := s.([]interface{})
+	if !ok {
+		v := reflect.ValueOf(s)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			programmerError(nil, "NewListFromGo given", v.Kind(),
+				"instead of a slice")
+		}
+		return _list_from_value(v, 0)
+	}
+	return _list_from_go(items)
+}
+
+func _list_from_go(s []interface{}) *List {
 	if len(s) == 0 {
 		return EmptyList
 	}
@@ -42,7 +58,7 @@ func UnserializeList(ser []byte, enc bool) (*List, bool) {
 	pos, ok := SlurpWS(ser, 0), false
 	var s []byte
 	if enc {
-		if ser[pos] != '{' {
+		if pos >= len(ser) || ser[pos] != '{' {
 			return nil, false
 		}
 		pos++
@@ -62,7 +78,7 @@ func UnserializeList(ser []byte, enc bool) (*List, bool) {
 			tail = head
 		}
 	}
-	if enc && ser[pos] != '}' {
+	if enc && (pos >= len(ser) || ser[pos] != '}') {
 		return nil, false
 	}
 	pos = SlurpWS(ser, pos)
@@ -0,0 +1,287 @@
+package gelo
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+//A module is a file of gelo named for its path in one of the file systems of
+//the module path, with the extension .gel, so the module util/strings is the
+//file util/strings.gel. A module is evaluated the first time it is imported,
+//in a namespace of its own whose parent is the outermost namespace of the VM
+//importing it, so it sees the Aliens registered with the VM and whatever the
+//VM defined before running its program, such as the prelude, but nothing of
+//the code importing it. Every variable the module sets at its top level is
+//exported, except arguments. As gelo looks up names where a command is
+//invoked rather than where it was written, an exported Alien, such as one
+//defined with the prelude's command, is bound as a command that invokes it in
+//a fork of the namespace of the module, so it can use the names the module
+//defines but not those of the code that invoked it. Quotes are exported as
+//they are, since they are as often data as code, and run where they are
+//invoked like any other quote.
+//
+//Modules are cached by a VM and the VMs it spawns, like quotas, so a module is
+//evaluated once however many times or from however many VMs it is imported.
+//A VM importing a module that another VM is evaluating waits for it, unless
+//that VM is waiting, directly or not, for a module the first is evaluating,
+//which is an import cycle.
+
+const _module_ext = ".gel"
+
+type _module struct {
+	name    string
+	exports *Dict
+	err     Error
+	done    chan struct{} //closed once the module has been evaluated
+	owner   *VM           //the VM evaluating the module
+}
+
+type _modules struct {
+	mux     sync.Mutex
+	path    []fs.FS
+	cache   map[string]*_module
+	waiting map[*VM]*_module //the module each VM is waiting for
+}
+
+//Set the file systems searched, in order, for the modules imported by vm and
+//the VMs it spawns from now on. This forgets the modules imported so far.
+func (vm *VM) SetModulePath(path ...fs.FS) {
+	vm._sanity("set the module path")
+	vm.modules = &_modules{path: path, cache: make(map[string]*_module),
+		waiting: make(map[*VM]*_module)}
+}
+
+//Evaluate the module name if it has not been imported yet and return its
+//exports. This is what the import command does before it binds the names.
+//Only call from the goroutine running vm, such as from an Alien.
+func (p *api) Import(name string) *Dict {
+	return NewDictFrom(p.vm._import(name).rep)
+}
+
+func importError(vm *VM, s ...interface{}) {
+	raise(vm, _make_kinded_error(vm, ErrKindImport, s))
+}
+
+//the chain of imports that would import name again, nil if there is none
+func (vm *VM) _importing_cycle(name string) []string {
+	for i, n := range vm.importing {
+		if n == name {
+			return append(vm.importing[i:len(vm.importing):len(vm.importing)],
+				name)
+		}
+	}
+	return nil
+}
+
+func (vm *VM) _import(name string) *Dict {
+	m := vm.modules
+	if m == nil || len(m.path) == 0 {
+		importError(vm, "Cannot import", name+": no module path is set")
+	}
+	file := name + _module_ext
+	if !fs.ValidPath(file) {
+		importError(vm, "Invalid module name:", name)
+	}
+	if cycle := vm._importing_cycle(name); cycle != nil {
+		importError(vm, "Import cycle:", strings.Join(cycle, " -> "))
+	}
+	m.mux.Lock()
+	mod, ok := m.cache[name]
+	if ok {
+		m._wait(vm, mod)
+		if mod.err != nil {
+			raise(vm, mod.err)
+		}
+		return mod.exports
+	}
+	mod = &_module{name: name, done: make(chan struct{}), owner: vm}
+	m.cache[name] = mod
+	m.mux.Unlock()
+
+	defer func() {
+		if mod.exports == nil {
+			//failed, let the next import try again
+			m.mux.Lock()
+			delete(m.cache, name)
+			m.mux.Unlock()
+			if mod.err == nil {
+				mod.err = _make_kinded_error(vm, ErrKindImport, "Import of",
+					name, "was abandoned")
+			}
+		}
+		close(mod.done)
+	}()
+	src, err := m._open(file)
+	if err != nil {
+		mod.err = _make_kinded_error(vm, ErrKindImport, "Cannot import",
+			name+":", err.Error())
+		raise(vm, mod.err)
+	}
+	defer src.Close()
+	ns, err2 := vm._eval_module(name, file, src)
+	if err2 != nil {
+		mod.err = err2
+		panic(err2)
+	}
+	mod.exports = _exports(ns)
+	return mod.exports
+}
+
+//waits for another VM to finish evaluating mod, m.mux must be held and is
+//released
+func (m *_modules) _wait(vm *VM, mod *_module) {
+	select {
+	case <-mod.done:
+		m.mux.Unlock()
+		return
+	default:
+	}
+	if cycle := m._waiting_cycle(vm, mod); cycle != nil {
+		m.mux.Unlock()
+		importError(vm, "Import cycle:", strings.Join(cycle, " -> "))
+	}
+	m.waiting[vm] = mod
+	m.mux.Unlock()
+	defer func() {
+		m.mux.Lock()
+		delete(m.waiting, vm)
+		m.mux.Unlock()
+	}()
+	select {
+	case <-mod.done:
+	case <-vm.kill_switch:
+		panic(kill_control_code(byte(0)))
+	case <-vm.done:
+		cancelled(vm, vm.ctx.Err())
+	}
+}
+
+//the chain of imports that would never finish if vm waited for mod, nil if
+//there is none. m.mux must be held.
+func (m *_modules) _waiting_cycle(vm *VM, mod *_module) []string {
+	var cycle []string
+	if n := len(vm.importing); n > 0 {
+		cycle = append(cycle, vm.importing[n-1])
+	}
+	for w := mod; w != nil; w = m.waiting[w.owner] {
+		cycle = append(cycle, w.name)
+		if w.owner == vm {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func (m *_modules) _open(file string) (fs.File, error) {
+	for _, fsys := range m.path {
+		f, err := fsys.Open(file)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("not found in the module path")
+}
+
+func (vm *VM) _outer_ns() *namespace {
+	ns := vm.cns
+	for ns.up != vm.top && ns.up != nil {
+		ns = ns.up
+	}
+	return ns
+}
+
+func (vm *VM) _eval_module(name, file string, src fs.File) (*namespace, Error) {
+	ns := newNamespace(vm._outer_ns())
+	saved := vm.cns
+	vm.cns = ns
+	vm.importing = append(vm.importing, name)
+	defer func() {
+		vm.cns = saved
+		vm.importing = vm.importing[:len(vm.importing)-1]
+	}()
+	if _, err := vm.API.EvalSource(file, src, nil); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+func _exports(ns *namespace) *Dict {
+	ns.mux.RLock()
+	defer ns.mux.RUnlock()
+	exports := make(map[string]Word, len(ns.dict.rep))
+	for k, v := range ns.dict.rep {
+		if k == "arguments" {
+			continue
+		}
+		if a, ok := v.(Alien); ok {
+			v = _module_command(ns, a)
+		}
+		exports[k] = v
+	}
+	return &Dict{rep: exports}
+}
+
+func _module_command(ns *namespace, cmd Alien) Alien {
+	return func(vm *VM, args *List, _ uint) Word {
+		saved := vm.cns
+		vm.cns = newNamespace(ns)
+		defer func() {
+			vm.cns = saved
+		}()
+		//can't tail invoke because the namespace would be restored
+		return vm.API.InvokeCmdOrElse(cmd, args)
+	}
+}
+
+//import module ['as prefix]? ['only name+]?
+func BI_import(vm *VM, args *List, ac uint) Word {
+	if ac == 0 {
+		ArgumentError(vm, "import", "module ['as prefix]? ['only name+]?",
+			args)
+	}
+	name := args.Value.Ser().String()
+	prefix := name[strings.LastIndex(name, "/")+1:]
+	var only []string
+	rest := args.Next
+	if rest != nil && rest.Value.Ser().String() == "as" {
+		if rest.Next == nil {
+			ArgumentError(vm, "import", "module ['as prefix]? ['only name+]?",
+				args)
+		}
+		prefix = rest.Next.Value.Ser().String()
+		rest = rest.Next.Next
+	}
+	if rest != nil {
+		if rest.Value.Ser().String() != "only" || rest.Next == nil {
+			ArgumentError(vm, "import", "module ['as prefix]? ['only name+]?",
+				args)
+		}
+		for rest = rest.Next; rest != nil; rest = rest.Next {
+			only = append(only, rest.Value.Ser().String())
+		}
+	}
+	exports := vm._import(name)
+	out := make(map[string]Word)
+	if only != nil {
+		for _, k := range only {
+			v, ok := exports.rep[k]
+			if !ok {
+				importError(vm, "Module", name, "does not export", k)
+			}
+			out[k] = v
+		}
+	} else {
+		for k, v := range exports.rep {
+			out[prefix+"."+k] = v
+		}
+	}
+	for k, v := range out {
+		vm.cns.set(StrToSym(k), v.DeepCopy())
+	}
+	return NewDictFrom(out)
+}
@@ -0,0 +1,136 @@
+package gelo_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"code.google.com/p/gelo"
+)
+
+func _module_vm(files map[string]string) *gelo.VM {
+	fsys := fstest.MapFS{}
+	for name, src := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(src)}
+	}
+	vm := _new_vm()
+	vm.SetModulePath(fsys)
+	return vm
+}
+
+func TestImport(t *testing.T) {
+	vm := _module_vm(map[string]string{
+		"m.gel": `set! colors {red green blue}
+set! greeting hello
+set! greet [ns capture { List $greeting @arguments }]`,
+	})
+	_eval(t, vm, "import m")
+	if got := _eval(t, vm, "llength $m.colors"); got != "3" {
+		t.Errorf("llength $m.colors = %s", got)
+	}
+	//greet sees the greeting of the module, not this one
+	_eval(t, vm, "set! greeting bye")
+	if got := _eval(t, vm, "m.greet world"); got != "{hello world}" {
+		t.Errorf("m.greet world = %s", got)
+	}
+	if got := _eval(t, vm, "import m as n only colors; llength $colors"); got != "3" {
+		t.Errorf("import only = %s", got)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	vm := _module_vm(map[string]string{
+		"a.gel":   "import b",
+		"b.gel":   "import a",
+		"bad.gel": "raise oops",
+	})
+	err := _eval_err(t, vm, "import a", gelo.ErrKindImport)
+	if !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("import cycle reported as %v", err)
+	}
+	_eval_err(t, vm, "import missing", gelo.ErrKindImport)
+	_eval_err(t, vm, "import ../a", gelo.ErrKindImport)
+	_eval_err(t, vm, "import bad", "oops")
+	_eval_err(t, _new_vm(), "import a", gelo.ErrKindImport)
+}
+
+//a cycle split between two VMs must be an error rather than both waiting for
+//the other forever
+func TestImportCycleAcrossVMs(t *testing.T) {
+	var both sync.WaitGroup
+	both.Add(2)
+	vm := _module_vm(map[string]string{
+		"a.gel": "barrier; import b",
+		"b.gel": "barrier; import a",
+	})
+	vm.Register("barrier", gelo.Alien(
+		func(*gelo.VM, *gelo.List, uint) gelo.Word {
+			both.Done()
+			both.Wait()
+			return gelo.Null
+		}))
+	errs := make(chan gelo.Error, 2)
+	for _, name := range []string{"a", "b"} {
+		child := vm.Spawn()
+		go func(name string) {
+			defer child.Destroy()
+			_, err := child.Do("import " + name)
+			errs <- err
+		}(name)
+	}
+	var cycle bool
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Fatal("import of a cycle succeeded")
+			}
+			if k := gelo.ErrorKind(err); k != gelo.ErrKindImport {
+				t.Errorf("expected an import error, got %s: %v", k, err)
+			}
+			cycle = cycle || strings.Contains(err.Error(), "Import cycle")
+		case <-time.After(5 * time.Second):
+			t.Fatal("imports of a cycle from two VMs never finished")
+		}
+	}
+	if !cycle {
+		t.Error("neither VM reported the import cycle")
+	}
+}
+
+func TestImportWaitCancelled(t *testing.T) {
+	release, started := make(chan bool), make(chan bool)
+	vm := _module_vm(map[string]string{"slow.gel": "block"})
+	vm.Register("block", gelo.Alien(
+		func(*gelo.VM, *gelo.List, uint) gelo.Word {
+			close(started)
+			<-release
+			return gelo.Null
+		}))
+	first, finished := vm.Spawn(), make(chan bool)
+	go func() {
+		defer close(finished)
+		if _, err := first.Do("import slow"); err != nil {
+			t.Errorf("import slow: %v", err)
+		}
+	}()
+	<-started
+	defer func() {
+		close(release)
+		<-finished
+		first.Destroy()
+	}()
+
+	second := vm.Spawn()
+	defer second.Destroy()
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	_, err := second.DoContext(ctx, "import slow")
+	if k := gelo.ErrorKind(err); err == nil || k != gelo.ErrKindCancelled {
+		t.Errorf("expected the wait to be cancelled, got %v", err)
+	}
+}
@@ -98,12 +98,14 @@ func (Ns *namespace_api) _is_blacklisted(s string) bool {
 //Call with nil to fork a blank namespace
 func (Ns *namespace_api) Fork(n *namespace) {
 	vm := Ns.vm
+	vm._check_ns_depth()
 	if n == nil {
 		vm.cns = newNamespace(vm.cns)
 	} else {
 		n.up = vm.cns
 		vm.cns = n
 	}
+	vm._hook_fork()
 }
 
 //returns false if we cannot Unfork (ie this is the topmost namespace)
@@ -115,6 +117,7 @@ func (Ns *namespace_api) Unfork() (*namespace, bool) {
 	out := vm.cns
 	vm.cns = out.up
 	out.up = nil
+	vm._hook_unfork()
 	return out, true
 }
 
@@ -2,15 +2,57 @@ package gelo
 
 import (
 	"math"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
+//A number is either exact, an integer or fraction of any size, or inexact, a
+//float64. Numbers written as integers, such as 18446744073709551617, or as
+//fractions, such as 1/3, are exact, as are those made from Go integers. The
+//sum, difference, product, quotient and modulus of exact numbers are exact,
+//while anything involving an inexact number is inexact. Exact fractions are
+//serialized as n/d so they read back the same.
+
 //Use this for ideal constants
 func NewNumber(f float64) *Number {
 	return &Number{num: f}
 }
 
+//Returns an exact integer, i is copied
+func NewNumberFromBigInt(i *big.Int) *Number {
+	return _exact_int(new(big.Int).Set(i))
+}
+
+//Returns an exact number, r is copied
+func NewNumberFromRat(r *big.Rat) *Number {
+	return _exact_rat(new(big.Rat).Set(r))
+}
+
+//i must not be changed afterwards
+func _exact_int(i *big.Int) *Number {
+	var f float64
+	if i.IsInt64() {
+		f = float64(i.Int64())
+	} else {
+		f, _ = new(big.Float).SetInt(i).Float64()
+	}
+	return &Number{num: f, i: i}
+}
+
+//r must not be changed afterwards
+func _exact_rat(r *big.Rat) *Number {
+	if r.IsInt() {
+		return _exact_int(new(big.Int).Set(r.Num()))
+	}
+	f, _ := r.Float64()
+	return &Number{num: f, r: r}
+}
+
 func NewNumberFrom(w Word) (*Number, bool) {
+	if d, ok := w.(*Decimal); ok {
+		return d.Number(), true
+	}
 	return NewNumberFromString(w.Ser().String())
 }
 
@@ -19,16 +61,27 @@ func NewNumberFromBytes(b []byte) (*Number, bool) {
 }
 
 func NewNumberFromString(s string) (*Number, bool) {
+	if i, ok := new(big.Int).SetString(s, 10); ok {
+		n := _exact_int(i)
+		n.ser = []byte(s)
+		return n, true
+	}
+	if strings.IndexByte(s, '/') >= 0 {
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, false
+		}
+		return _exact_rat(r), true
+	}
 	num, err := strconv.ParseFloat(s, 64)
 	if err != nil {
 		return nil, false
 	}
-	return &Number{num, []byte(s)}, true
+	return &Number{num: num, ser: []byte(s)}, true
 }
 
 func NewNumberFromGo(in interface{}) (*Number, bool) {
 	var out float64
-	var ser []byte
 	switch n := in.(type) {
 	default:
 		return nil, false
@@ -36,69 +89,256 @@ func NewNumberFromGo(in interface{}) (*Number, bool) {
 		return nil, false
 	case *Number:
 		return n, true
+	case *Decimal:
+		return n.Number(), true
 	case Word:
 		return NewNumberFrom(n)
 	case []byte:
 		return NewNumberFromBytes(n)
 	case string:
 		return NewNumberFromString(n)
+	case *big.Int:
+		return NewNumberFromBigInt(n), true
+	case *big.Rat:
+		return NewNumberFromRat(n), true
 	case float64:
 		out = n
 	case float32:
 		out = float64(n)
 	case int64:
-		out = float64(n)
+		return _exact_int(big.NewInt(n)), true
 	case int32:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case int16:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case int8:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case int:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case uint64:
-		out = float64(n)
+		return _exact_int(new(big.Int).SetUint64(n)), true
 	case uint32:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case uint16:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case uint8:
-		out = float64(n)
+		return _exact_int(big.NewInt(int64(n))), true
 	case uint:
-		out = float64(n)
+		return _exact_int(new(big.Int).SetUint64(uint64(n))), true
 	}
-	return &Number{out, ser}, true
+	return &Number{num: out}, true
 }
 
+//The value of n, or the nearest float64 to it if n is exact
 func (n *Number) Real() float64 {
 	return n.num
 }
 
 func (n *Number) Int() (int64, bool) {
+	if n.i != nil {
+		if !n.i.IsInt64() {
+			return 0, false
+		}
+		return n.i.Int64(), true
+	}
+	if n.r != nil {
+		return 0, false
+	}
 	num := n.num
-	if math.IsNaN(num) || math.Mod(num, 1) != 0 || math.MaxInt64 < num {
+	//float64(math.MaxInt64) rounds up to 1<<63
+	if math.IsNaN(num) || math.Mod(num, 1) != 0 || num >= 1<<63 ||
+		num < -1<<63 {
 		return 0, false
 	}
 	return int64(num), true
 }
 
+//Returns n if it is an exact integer
+func (n *Number) BigInt() (*big.Int, bool) {
+	if n.i == nil {
+		return nil, false
+	}
+	return new(big.Int).Set(n.i), true
+}
+
+//Returns n if it is exact
+func (n *Number) Rat() (*big.Rat, bool) {
+	if !n.Exact() {
+		return nil, false
+	}
+	return new(big.Rat).Set(n._rat()), true
+}
+
+func (n *Number) Exact() bool {
+	return n.i != nil || n.r != nil
+}
+
+//n as a fraction, which must not be changed, n must be exact
+func (n *Number) _rat() *big.Rat {
+	if n.r != nil {
+		return n.r
+	}
+	return new(big.Rat).SetInt(n.i)
+}
+
+//Whether n is a whole number, exact or not
+func (n *Number) IsInteger() bool {
+	if n.Exact() {
+		return n.i != nil
+	}
+	return !math.IsInf(n.num, 0) && math.Mod(n.num, 1) == 0
+}
+
+//Returns -1, 0 or 1 as n is negative, zero or positive, and 0 for NaN
+func (n *Number) Sign() int {
+	switch {
+	case n.i != nil:
+		return n.i.Sign()
+	case n.r != nil:
+		return n.r.Sign()
+	case n.num < 0:
+		return -1
+	case n.num > 0:
+		return 1
+	}
+	return 0
+}
+
+func (n *Number) IsZero() bool {
+	if n.Exact() {
+		return n.Sign() == 0
+	}
+	return n.num == 0
+}
+
+//Returns -1, 0 or 1 as n is less than, equal to or greater than o, or ok false
+//if either is NaN. Exact numbers are compared exactly, even with inexact ones.
+func (n *Number) Cmp(o *Number) (c int, ok bool) {
+	switch {
+	case n.i != nil && o.i != nil:
+		return n.i.Cmp(o.i), true
+	case n.Exact() && o.Exact():
+		return n._rat().Cmp(o._rat()), true
+	}
+	a, b := n.num, o.num
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return 0, false
+	}
+	if n.Exact() && !math.IsInf(b, 0) {
+		return n._rat().Cmp(new(big.Rat).SetFloat64(b)), true
+	}
+	if o.Exact() && !math.IsInf(a, 0) {
+		return new(big.Rat).SetFloat64(a).Cmp(o._rat()), true
+	}
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	}
+	return 0, true
+}
+
+func (n *Number) Add(o *Number) *Number {
+	switch {
+	case n.i != nil && o.i != nil:
+		return _exact_int(new(big.Int).Add(n.i, o.i))
+	case n.Exact() && o.Exact():
+		return _exact_rat(new(big.Rat).Add(n._rat(), o._rat()))
+	}
+	return NewNumber(n.num + o.num)
+}
+
+func (n *Number) Sub(o *Number) *Number {
+	switch {
+	case n.i != nil && o.i != nil:
+		return _exact_int(new(big.Int).Sub(n.i, o.i))
+	case n.Exact() && o.Exact():
+		return _exact_rat(new(big.Rat).Sub(n._rat(), o._rat()))
+	}
+	return NewNumber(n.num - o.num)
+}
+
+func (n *Number) Mul(o *Number) *Number {
+	switch {
+	case n.i != nil && o.i != nil:
+		return _exact_int(new(big.Int).Mul(n.i, o.i))
+	case n.Exact() && o.Exact():
+		return _exact_rat(new(big.Rat).Mul(n._rat(), o._rat()))
+	}
+	return NewNumber(n.num * o.num)
+}
+
+//Returns n / o, which is a fraction if n and o are exact and o does not divide
+//n. o must not be zero if both are exact.
+func (n *Number) Quo(o *Number) *Number {
+	if n.Exact() && o.Exact() {
+		return _exact_rat(new(big.Rat).Quo(n._rat(), o._rat()))
+	}
+	return NewNumber(n.num / o.num)
+}
+
+//Returns the remainder of n / o truncated toward zero, which has the sign of n
+//as with math.Mod. It is NaN if o is zero.
+func (n *Number) Mod(o *Number) *Number {
+	if n.Exact() && o.Exact() && o.Sign() != 0 {
+		if n.i != nil && o.i != nil {
+			return _exact_int(new(big.Int).Rem(n.i, o.i))
+		}
+		q := new(big.Rat).Quo(n._rat(), o._rat())
+		t := new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+		return _exact_rat(t.Sub(n._rat(), t.Mul(t, o._rat())))
+	}
+	return NewNumber(math.Mod(n.num, o.num))
+}
+
+func (n *Number) Neg() *Number {
+	switch {
+	case n.i != nil:
+		return _exact_int(new(big.Int).Neg(n.i))
+	case n.r != nil:
+		return _exact_rat(new(big.Rat).Neg(n.r))
+	}
+	return NewNumber(-n.num)
+}
+
+func (n *Number) Abs() *Number {
+	switch {
+	case n.i != nil:
+		return _exact_int(new(big.Int).Abs(n.i))
+	case n.r != nil:
+		return _exact_rat(new(big.Rat).Abs(n.r))
+	}
+	return NewNumber(math.Abs(n.num))
+}
+
 func (n *Number) Ser() Symbol {
 	if n.ser == nil {
-		if i, ok := n.Int(); ok {
-			n.ser = []byte(strconv.FormatInt(i, 10))
-		} else {
-			n.ser = []byte(strconv.FormatFloat(n.num, 'g', -1, 64))
+		switch {
+		case n.i != nil:
+			n.ser = []byte(n.i.String())
+		case n.r != nil:
+			n.ser = []byte(n.r.String())
+		default:
+			if i, ok := n.Int(); ok {
+				n.ser = []byte(strconv.FormatInt(i, 10))
+			} else {
+				n.ser = []byte(strconv.FormatFloat(n.num, 'g', -1, 64))
+			}
 		}
 	}
 	return BytesToSym(n.ser)
 }
 
 func (n *Number) Equals(w Word) bool {
-	on, ok := w.(*Number)
-	if !ok {
-		return false
+	switch on := w.(type) {
+	case *Number:
+		c, ok := n.Cmp(on)
+		return ok && c == 0
+	case *Decimal:
+		return on.Equals(n)
 	}
-	return on.num == n.num //XXX should check that they are within mach eps
+	return false
 }
 
 func (n *Number) Copy() Word {
@@ -107,7 +347,8 @@ func (n *Number) Copy() Word {
 		ser = make([]byte, len(n.ser))
 		copy(ser, n.ser)
 	}
-	return &Number{n.num, ser}
+	//the exact value is never changed so it can be shared
+	return &Number{n.num, ser, n.i, n.r}
 }
 
 func (n *Number) DeepCopy() Word {
@@ -0,0 +1,282 @@
+package gelo
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+//An object is an Alien made by NewObject that lets scripts use a Go struct
+//through a pointer to it, so they see and change the struct itself rather
+//than a copy of it:
+//
+//	obj                    a dict of the fields and their values
+//	obj get field
+//	obj set! field value
+//	obj method args*
+//
+//The exported fields of the struct are named as in Go unless they are tagged,
+//`gelo:"name"` renames a field, `gelo:"-"` hides it and `gelo:"name,readonly"`
+//or `gelo:",readonly"` lets scripts get it but not set it. Fields are
+//converted as the arguments and results of the Aliens made by
+//NewAlienFromFunc are, except that a field that is a struct or a pointer to
+//one is got as an object unless Convert has a case or a converter for it, as
+//it does for time.Time. Fields whose type is an encoding.TextUnmarshaler, such
+//as time.Time, are set from the serialization of the word. Fields that cannot
+//be converted are left out. The methods are those of the pointer, invoked as
+//Aliens made by NewAlienFromFunc, leaving out those that cannot be converted.
+//
+//If the pointer is a sync.Locker, for instance because the struct embeds a
+//sync.Mutex, it is locked while a field is got or set, so the host can share
+//the struct with scripts by locking it too. Its Lock and Unlock methods are
+//not exposed and the other methods are left to lock it themselves. An object
+//got from a field is locked with the lock of the object it was got from, as
+//its struct is part of that one, unless it is got through a pointer to a
+//struct that is a sync.Locker of its own.
+
+type _object_field struct {
+	index    []int
+	readonly bool
+	conv     _go_arg //nil if the field cannot be set
+	object   bool    //a struct or a pointer to one, got as an object
+}
+
+type _object_type struct {
+	fields  map[string]*_object_field
+	methods []string //those that can be made into Aliens
+}
+
+var _object_types sync.Map //reflect.Type of the pointer to *_object_type
+
+type _object struct {
+	name    string
+	v       reflect.Value //the struct
+	lock    sync.Locker
+	typ     *_object_type
+	methods map[string]Alien
+}
+
+//Returns an object for ptr, which must be a pointer to a struct. The name is
+//used in the errors raised by the object.
+func NewObject(name string, ptr interface{}) Alien {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() ||
+		v.Elem().Kind() != reflect.Struct {
+		programmerError(nil, "NewObject given", v.Type(),
+			"instead of a pointer to a struct")
+	}
+	lock, _ := ptr.(sync.Locker)
+	return _new_object(name, v, lock)
+}
+
+//v is a non-nil pointer to a struct
+func _new_object(name string, v reflect.Value, lock sync.Locker) Alien {
+	o := &_object{name: name, v: v.Elem(), lock: lock,
+		typ: _object_type_of(v.Type())}
+	o.methods = make(map[string]Alien, len(o.typ.methods))
+	for _, m := range o.typ.methods {
+		o.methods[m] = _func_alien(name+" "+m, v.MethodByName(m))
+	}
+	return o.invoke
+}
+
+func _object_type_of(t reflect.Type) *_object_type {
+	if ot, ok := _object_types.Load(t); ok {
+		return ot.(*_object_type)
+	}
+	ot := &_object_type{fields: make(map[string]*_object_field)}
+	for _, sf := range reflect.VisibleFields(t.Elem()) {
+		name, readonly, ok := _field_tag(sf)
+		if !ok {
+			continue
+		}
+		f := &_object_field{index: sf.Index, readonly: readonly}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && !sf.Type.Implements(_word_type) &&
+			!_converted_specially(sf.Type) {
+			f.object = true
+		} else if !_can_convert_result(sf.Type) {
+			continue
+		}
+		f.conv = _field_converter(sf.Type)
+		ot.fields[name] = f
+	}
+	locker := t.Implements(reflect.TypeOf((*sync.Locker)(nil)).Elem())
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if locker && (m.Name == "Lock" || m.Name == "Unlock" ||
+			m.Name == "TryLock") {
+			continue
+		}
+		//the type of the method includes the receiver
+		if _func_convertible(m.Type, 1) {
+			ot.methods = append(ot.methods, m.Name)
+		}
+	}
+	ot2, _ := _object_types.LoadOrStore(t, ot)
+	return ot2.(*_object_type)
+}
+
+//converts the words a field of type t is set to, nil if it cannot be set
+func _field_converter(t reflect.Type) _go_arg {
+	if conv, _ := _arg_converter(t); conv != nil {
+		return conv
+	}
+	if !reflect.PtrTo(t).Implements(_text_unmarshaler_type) {
+		return nil
+	}
+	return func(vm *VM, w Word) reflect.Value {
+		v := reflect.New(t)
+		u := v.Interface().(encoding.TextUnmarshaler)
+		if err := u.UnmarshalText(w.Ser().Bytes()); err != nil {
+			RuntimeError(vm, "Cannot set a", t.String()+" to", w.Ser().String()+
+				":", err.Error())
+		}
+		return v.Elem()
+	}
+}
+
+//Returns the name a field is known by in gelo and whether it is read-only, or
+//ok false if the field is hidden
+func _field_tag(sf reflect.StructField) (name string, readonly, ok bool) {
+	if !sf.IsExported() || sf.Anonymous {
+		return "", false, false
+	}
+	tag := sf.Tag.Get("gelo")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = sf.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "readonly" {
+			readonly = true
+		}
+	}
+	return name, readonly, true
+}
+
+//whether _func_alien can make the function of type t into an Alien, ignoring
+//the first skip parameters
+func _func_convertible(t reflect.Type, skip int) bool {
+	for i := skip; i < t.NumIn(); i++ {
+		in := t.In(i)
+		if i == skip && in == _vm_type {
+			continue
+		}
+		if i == t.NumIn()-1 && t.IsVariadic() {
+			in = in.Elem()
+		}
+		if conv, _ := _arg_converter(in); conv == nil {
+			return false
+		}
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		out := t.Out(i)
+		if i == t.NumOut()-1 && out == _error_type {
+			continue
+		}
+		if !_can_convert_result(out) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *_object) invoke(vm *VM, args *List, ac uint) Word {
+	if ac == 0 {
+		return o.fields(vm)
+	}
+	cmd := args.Value.Ser().String()
+	switch cmd {
+	case "get":
+		if ac != 2 {
+			ArgumentError(vm, o.name+" get", "field", args.Next)
+		}
+		name := args.Next.Value.Ser().String()
+		f := o.field(vm, args.Next.Value)
+		if o.lock != nil {
+			o.lock.Lock()
+			defer o.lock.Unlock()
+		}
+		return o.get(vm, name, f)
+	case "set!":
+		if ac != 3 {
+			ArgumentError(vm, o.name+" set!", "field value", args.Next)
+		}
+		name := args.Next.Value.Ser().String()
+		f := o.field(vm, args.Next.Value)
+		if f.readonly || f.conv == nil {
+			RuntimeError(vm, "Field", name, "of", o.name, "is read-only")
+		}
+		o.set(vm, f, args.Next.Next.Value)
+		return args.Next.Next.Value
+	}
+	m, ok := o.methods[cmd]
+	if !ok {
+		RuntimeError(vm, cmd, "is not a method of", o.name)
+	}
+	return vm.API.TailInvokeCmd(m, args.Next)
+}
+
+func (o *_object) field(vm *VM, w Word) *_object_field {
+	f, ok := o.typ.fields[w.Ser().String()]
+	if !ok {
+		RuntimeError(vm, w, "is not a field of", o.name)
+	}
+	return f
+}
+
+func (o *_object) fields(vm *VM) Word {
+	if o.lock != nil {
+		o.lock.Lock()
+		defer o.lock.Unlock()
+	}
+	d := make(map[string]Word, len(o.typ.fields))
+	for name, f := range o.typ.fields {
+		d[name] = o.get(vm, name, f)
+	}
+	return &Dict{rep: d}
+}
+
+//called with the lock held
+func (o *_object) get(vm *VM, name string, f *_object_field) Word {
+	v, err := o.v.FieldByIndexErr(f.index)
+	if err != nil {
+		RuntimeError(vm, "Cannot get a field of", o.name+":", err.Error())
+	}
+	if !f.object {
+		return _from_go(v)
+	}
+	name = o.name + "." + name
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return Null
+		}
+		if lock, ok := v.Interface().(sync.Locker); ok {
+			return _new_object(name, v, lock)
+		}
+		return _new_object(name, v, o.lock)
+	}
+	return _new_object(name, v.Addr(), o.lock)
+}
+
+func (o *_object) set(vm *VM, f *_object_field, w Word) {
+	//converted first as conversion can raise an error
+	val := f.conv(vm, w)
+	if o.lock != nil {
+		o.lock.Lock()
+		defer o.lock.Unlock()
+	}
+	v, err := o.v.FieldByIndexErr(f.index)
+	if err != nil {
+		RuntimeError(vm, "Cannot set a field of", o.name+":", err.Error())
+	}
+	v.Set(val)
+}
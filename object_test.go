@@ -0,0 +1,107 @@
+package gelo_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type _inner struct {
+	X int
+}
+
+type _config struct {
+	sync.Mutex
+	Name    string
+	Port    int    `gelo:"port"`
+	Secret  string `gelo:"-"`
+	Version int    `gelo:",readonly"`
+	Created time.Time
+	In      _inner
+	Ptr     *_inner
+	locks   int
+}
+
+func (c *_config) Lock() {
+	c.Mutex.Lock()
+	c.locks++
+}
+
+func (c *_config) Greet(who string) string {
+	return c.Name + " greets " + who
+}
+
+func TestObjectFields(t *testing.T) {
+	cfg := &_config{Name: "srv", Port: 80, Version: 2,
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	vm := _new_vm()
+	vm.RegisterObject("cfg", cfg)
+	for _, c := range []struct{ src, want string }{
+		{"cfg get Name", "srv"},
+		{"cfg get port", "80"},
+		{"cfg set! port 8080; cfg get port", "8080"},
+		{"cfg get Version", "2"},
+		{"cfg get Created", "2020-01-02T03:04:05Z"},
+		{"cfg set! Created 2021-06-07T08:09:10Z; cfg get Created",
+			"2021-06-07T08:09:10Z"},
+		{"cfg get Ptr", ""},
+		{"cfg Greet you", "srv greets you"},
+	} {
+		if got := _eval(t, vm, c.src); got != c.want {
+			t.Errorf("%s = %s, want %s", c.src, got, c.want)
+		}
+	}
+	if cfg.Port != 8080 || cfg.Created.Year() != 2021 {
+		t.Errorf("the struct was not changed: %+v", cfg)
+	}
+	for _, src := range []string{"cfg get Secret", "cfg get locks",
+		"cfg set! Version 3", "cfg set! port eighty",
+		"cfg set! Created yesterday", "cfg Lock", "cfg get"} {
+		_eval_err(t, vm, src, "runtime-error")
+	}
+	if !strings.Contains(_eval(t, vm, "cfg"), "Created") {
+		t.Error("the dict of the fields has no Created")
+	}
+}
+
+func TestObjectNested(t *testing.T) {
+	cfg := &_config{Ptr: &_inner{}}
+	vm := _new_vm()
+	vm.RegisterObject("cfg", cfg)
+	_eval(t, vm, "[cfg get In] set! X 5; [cfg get Ptr] set! X 6")
+	if cfg.In.X != 5 || cfg.Ptr.X != 6 {
+		t.Errorf("nested objects set %d and %d", cfg.In.X, cfg.Ptr.X)
+	}
+	cfg.Lock()
+	before := cfg.locks
+	cfg.Unlock()
+	_eval(t, vm, "set! in [cfg get In]; $in set! X 7; $in get X")
+	if cfg.locks < before+2 {
+		t.Error("the nested object did not take the lock of its parent")
+	}
+}
+
+//run with -race, the script writes through the nested object while the host
+//reads under the lock
+func TestObjectNestedLocked(t *testing.T) {
+	cfg := &_config{}
+	vm := _new_vm()
+	vm.RegisterObject("cfg", cfg)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			cfg.Lock()
+			_ = cfg.In.X
+			cfg.Unlock()
+		}
+	}()
+	_eval(t, vm, "set! in [cfg get In]; repeat 100 { $in set! X @arguments }")
+	<-done
+	cfg.Lock()
+	defer cfg.Unlock()
+	if cfg.In.X != 99 {
+		t.Errorf("In.X = %d, want 99", cfg.In.X)
+	}
+}
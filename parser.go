@@ -1,5 +1,7 @@
 package gelo
 
+import "strconv"
+
 type _ast byte
 
 const (
@@ -39,24 +41,53 @@ var _ctrlchars map[byte]byte = map[byte]byte{
 	'n': '\n', 'r': '\r', 'f': '\f', 't': '\t', 'a': '\a', 'b': '\b', 'v': '\v',
 }
 
+//The location of a parsed node in its source. Line and Column count from 1,
+//Column in bytes. A Line of 0 means the position is unknown, as is the case
+//for code built at runtime rather than parsed.
+type SrcPos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p SrcPos) Known() bool {
+	return p.Line != 0
+}
+
+func (p SrcPos) String() string {
+	if !p.Known() {
+		return "unknown position"
+	}
+	pos := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.File == "" {
+		return pos
+	}
+	return p.File + ":" + pos
+}
+
 type sNode struct {
 	tag  _ast
 	val  interface{}
 	next *sNode
+	pos  SrcPos
 }
 
 type command struct {
 	cmd  *sNode
 	next *command
+	pos  SrcPos
 }
 
 type _parser struct {
-	record bool
-	escm   _esc_mode
-	ch     _lexeme
-	cur    []byte
-	src    reader
-	buf    *buffer
+	record    bool
+	escm      _esc_mode
+	ch        _lexeme
+	cur       []byte
+	src       reader
+	buf       *buffer
+	file      string
+	line, col int
+	nl        bool //last char read was a newline
 }
 
 func (p *_parser) _adv() {
@@ -66,7 +97,29 @@ func (p *_parser) _adv() {
 	_, err := p.src.Read(p.cur)
 	if err != nil {
 		p.ch = _eof
+		return
+	}
+	if p.nl && p.line != 0 { //line 0 means we are not tracking positions
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
 	}
+	p.nl = p.cur[0] == '\n'
+}
+
+//position of the last character read
+func (p *_parser) _pos() SrcPos {
+	if p.line == 0 {
+		return SrcPos{}
+	}
+	return SrcPos{p.file, p.line, p.col}
+}
+
+func (p *_parser) _synerr(s ...interface{}) {
+	e := _make_error(nil, s)
+	e.pos = p._pos()
+	panic(&ErrSyntax{e})
 }
 
 func (p *_parser) _next() {
@@ -82,7 +135,7 @@ func (p *_parser) _next() {
 	if p.cur[0] == '\\' {
 		p._adv() //get char after \
 		if p.ch == _eof {
-			SyntaxError("Cannot escape the end of file")
+			p._synerr("Cannot escape the end of file")
 		}
 		ch := p.cur[0]
 
@@ -164,6 +217,7 @@ func (p *_parser) _read_out() []byte {
 
 func (p *_parser) _parse_word() *sNode {
 	var head *sNode
+	pos := p._pos()
 	try_num := false
 	join := func(n *sNode) {
 		if head != nil {
@@ -173,19 +227,18 @@ func (p *_parser) _parse_word() *sNode {
 		}
 	}
 	if p.ch == _l_splice {
-		join(&sNode{synSplice, nil, nil})
+		join(&sNode{synSplice, nil, nil, pos})
 		p._next()
 	} else if p.ch == _l_indirect {
-		join(&sNode{synIndirect, nil, nil})
+		join(&sNode{synIndirect, nil, nil, pos})
 		p._next()
 	}
 	//handle [] {} "", these conditions only hold if there was a sigil
 	switch p.ch {
 	case _eol, _eof, _l_space:
-		SyntaxError("Sigil precedes nothing")
+		p._synerr("Sigil precedes nothing")
 	case _lo_clause:
-		p._next()
-		join(p._parse_line(true))
+		join(p._parse_clause())
 		return head
 	case _lo_quote:
 		join(p._parse_quote())
@@ -195,6 +248,7 @@ func (p *_parser) _parse_word() *sNode {
 		return head
 	}
 	//just a word, slurp till we hit not a word
+	pos = p._pos()
 	p.record = true
 	//if first ch is a number or - or + or . we might have a number
 	rch := p.cur[0]
@@ -214,11 +268,11 @@ func (p *_parser) _parse_word() *sNode {
 				n, ok := NewNumberFromGo(r)
 				if ok {
 					n.ser = r //cache serilization
-					join(&sNode{synLiteral, n, nil})
+					join(&sNode{synLiteral, n, nil, pos})
 					return head
 				}
 			}
-			join(&sNode{synLiteral, intern(r), nil})
+			join(&sNode{synLiteral, intern(r), nil, pos})
 			return head
 		}
 		p._next()
@@ -235,17 +289,18 @@ func (p *_parser) _rquote(record bool) {
 	//some machinery had to be duplicated here to avoid complicating the rest
 	p._adv() //eof will be caught in below loop
 	if p.ch == _eof {
-		SyntaxError("{ without }")
+		p._synerr("{ without }")
 	}
 	switch p.cur[0] {
 	case '\\':
 		p._adv()
 		if p.ch == _eof {
-			SyntaxError("Cannot escape the end of file")
+			p._synerr("Cannot escape the end of file")
 		}
 		p.buf.WriteString("\\")
 	case '{':
-		p.ch = _lo_quote
+		//the loop below steps over it without seeing it
+		depth++
 	case '}':
 		return
 	}
@@ -262,7 +317,7 @@ func (p *_parser) _rquote(record bool) {
 				return
 			}
 		case _eof:
-			SyntaxError("{ without }")
+			p._synerr("{ without }")
 		}
 		p._next()
 	}
@@ -270,6 +325,9 @@ func (p *_parser) _rquote(record bool) {
 
 func (p *_parser) _parse_quote() *sNode {
 	var q Quote
+	pos := p._pos()
+	//the body starts just after the {
+	body := SrcPos{pos.File, pos.Line, pos.Column + 1}
 	p._rquote(true)
 	out := p._read_out()
 	p._next()
@@ -277,32 +335,42 @@ func (p *_parser) _parse_quote() *sNode {
 		//got {}
 		q = Noop
 	} else {
-		q = &protected_quote{&quote{false, nil, out}}
+		q = &protected_quote{&quote{false, nil, out, body, nil}}
 	}
-	return &sNode{synQuote, q, nil}
+	return &sNode{synQuote, q, nil, pos}
 }
 
 func (p *_parser) _parse_string() *sNode {
+	pos := p._pos()
 	p.escm = _str
 	p._next()
 	if p.ch == _l_str {
 		//got ""
 		p._next()
 		p.escm = _reg
-		return &sNode{synLiteral, Null, nil}
+		return &sNode{synLiteral, Null, nil, pos}
 	}
 	p.record = true
 	for ; p.ch != _l_str; p._next() {
 		if p.ch == _eof {
-			SyntaxError("\" without \"")
+			p._synerr("\" without \"")
 		}
 	}
-	val := &sNode{synLiteral, intern(p._read_out()), nil}
+	val := &sNode{synLiteral, intern(p._read_out()), nil, pos}
 	p.escm = _reg
 	p._next()
 	return val
 }
 
+//parses a [clause], called with the [ as the current character
+func (p *_parser) _parse_clause() *sNode {
+	pos := p._pos()
+	p._next()
+	n := p._parse_line(true)
+	n.pos = pos
+	return n
+}
+
 func (p *_parser) _parse_line(clause bool) *sNode {
 	var head, node *sNode
 	join := func(n *sNode) {
@@ -317,13 +385,13 @@ func (p *_parser) _parse_line(clause bool) *sNode {
 	for ; p.ch == _l_space; p._next() {
 	} //skip leading ws
 	if clause && p.ch == _lc_clause {
-		SyntaxError("[] invalid. Use {} for no-op")
+		p._synerr("[] invalid. Use {} for no-op")
 	}
 	switch p.ch {
 	case _eol, _eof:
 		//just a blank line
 		if clause {
-			SyntaxError("[ without ]")
+			p._synerr("[ without ]")
 		}
 		p._next() //only needed for eol, no effect on eof
 		return nil
@@ -346,22 +414,21 @@ func (p *_parser) _parse_line(clause bool) *sNode {
 		} //skip ws
 		switch p.ch {
 		case _lc_quote:
-			SyntaxError("} before {")
+			p._synerr("} before {")
 		case _eof, _eol:
 			if clause {
-				SyntaxError("[ without ]")
+				p._synerr("[ without ]")
 			}
 			p._next()
 			return head
 		case _lc_clause:
 			if !clause {
-				SyntaxError("] before [")
+				p._synerr("] before [")
 			}
 			p._next()
-			return &sNode{synClause, head, nil}
+			return &sNode{synClause, head, nil, SrcPos{}}
 		case _lo_clause:
-			p._next()
-			join(p._parse_line(true))
+			join(p._parse_clause())
 		case _l_str:
 			join(p._parse_string())
 		case _lo_quote:
@@ -373,18 +440,20 @@ func (p *_parser) _parse_line(clause bool) *sNode {
 	panic("parse line in impossible state") //Issue 65
 }
 
-func parse(in reader) *command {
+//pos is the position of the first character of in
+func parse(in reader, pos SrcPos) *command {
 	var head, tail *command
 	var n *sNode
 	p := new(_parser)
 	p.src = in
+	p.file, p.line, p.col = pos.File, pos.Line, pos.Column-1
 	p.cur = make([]byte, 1, 1)
 	p.buf = newBuf(0)
 	p.escm = _reg
 	p._next() //prime l'pump
 	for p.ch != _eof {
 		if n = p._parse_line(false); n != nil {
-			node := &command{n, nil}
+			node := &command{n, nil, n.pos}
 			if head != nil {
 				tail.next = node
 				tail = tail.next
@@ -394,6 +463,6 @@ func parse(in reader) *command {
 			}
 		}
 	}
-	parse_trace("quotation has parsed to", head)
+	parse_trace(nil, "quotation has parsed to", head)
 	return head
 }
@@ -0,0 +1,67 @@
+package gelo_test
+
+import (
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+//runs src as a program, so syntax errors in it are returned rather than
+//panicking as they do in Do
+func _run(vm *gelo.VM, src string) (string, gelo.Error) {
+	ret, err := vm.Run("test.gel", strings.NewReader(src), nil)
+	if err != nil {
+		return "", err
+	}
+	return ret.Ser().String(), nil
+}
+
+//a quote whose body starts with { used to end at the first }
+func TestParseQuoteStartingWithQuote(t *testing.T) {
+	vm := _new_vm()
+	for _, c := range []struct{ src, want string }{
+		{"llength [List {{a b} c} d]", "2"},
+		{"llength [List {{a b} {c d} e}]", "1"},
+		{"llength [List {{{a} b}} {{}}]", "2"},
+		{"dict [Dict {{a 1} {b 2}}] get b", "2"},
+	} {
+		if got, err := _run(vm, c.src); err != nil || got != c.want {
+			t.Errorf("%s = %s, %v, want %s", c.src, got, err, c.want)
+		}
+	}
+	if _, err := _run(vm, "llength {{a b}"); gelo.ErrorKind(err) !=
+		gelo.ErrKindSyntax {
+		t.Errorf("unclosed quote gave %v", err)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	vm := _new_vm()
+	_, err := vm.Run("p.gel", strings.NewReader(
+		"set! x 1\nset! y 2\n  + $x [nope]\n"), nil)
+	r, ok := err.(*gelo.ErrRuntime)
+	if !ok {
+		t.Fatalf("expected a runtime error, got %v", err)
+	}
+	if p := r.Position(); p != (gelo.SrcPos{"p.gel", 3, 9}) {
+		t.Errorf("runtime error at %v, want p.gel:3:9", p)
+	}
+	if !strings.HasPrefix(err.Error(), "p.gel:3:9: ") {
+		t.Errorf("the message does not start with the position: %v", err)
+	}
+	_, err = vm.Run("q.gel", strings.NewReader(
+		"set! x 1\nllength \"a\n"), nil)
+	s, ok := err.(*gelo.ErrSyntax)
+	if !ok {
+		t.Fatalf("expected a syntax error, got %v", err)
+	}
+	if p := s.Position(); p.File != "q.gel" || p.Line != 2 {
+		t.Errorf("syntax error at %v, want q.gel:2", p)
+	}
+	//Do has no file name
+	if err := _eval_err(t, vm, "\n + 1 x", gelo.ErrKindRuntime); !strings.
+		HasPrefix(err.Error(), "2:2: ") {
+		t.Errorf("the message does not start with the position: %v", err)
+	}
+}
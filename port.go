@@ -1,5 +1,7 @@
 package gelo
 
+import "context"
+
 type Chan struct {
 	C      chan Word
 	closed bool
@@ -13,17 +15,37 @@ func (c *Chan) Send(w Word) {
 	c.C <- w.DeepCopy()
 }
 
+func (c *Chan) SendContext(ctx context.Context, w Word) error {
+	select {
+	case c.C <- w.DeepCopy():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Chan) Recv() (w Word) {
+	w, _ = c.RecvContext(context.Background())
+	return
+}
+
+func (c *Chan) RecvContext(ctx context.Context) (w Word, err error) {
 	if c.closed {
-		return Null
+		return Null, nil
+	}
+	var ok bool
+	select {
+	case w, ok = <-c.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	w, c.closed = <-c.C
+	c.closed = !ok
 	if c.closed {
-		return Null
+		return Null, nil
 	} else if w == nil {
-		return EmptyList
+		return EmptyList, nil
 	}
-	return w.DeepCopy()
+	return w.DeepCopy(), nil
 }
 
 func (c *Chan) Close() {
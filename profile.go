@@ -0,0 +1,466 @@
+package gelo
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"runtime/metrics"
+	"sort"
+	"time"
+)
+
+//The profiler follows the VM's frames. Every command evaluated while
+//profiling is a node in a tree of the commands that called it, keyed by its
+//name and where it was called from, so the same command called from
+//different places is profiled separately. A command in tail position takes
+//the place of its caller in the tree as it does in the frames, so loops
+//written with tail recursion do not make the tree deeper. A quote run in
+//place of the command that returned it, such as the branch of an if, is part
+//of that command rather than a command of its own, so it is not counted as a
+//call and what it invokes is profiled as invoked by that command.
+//
+//Allocations are read from the counters of the Go runtime, which are shared
+//by the whole process and updated in batches. They are only meaningful in
+//aggregate, and include whatever other goroutines allocate, such as VMs
+//spawned by go, which are not themselves profiled.
+
+const _allocs_metric = "/gc/heap/allocs:objects"
+
+type _prof_node struct {
+	name     string
+	pos      SrcPos
+	parent   *_prof_node
+	children map[string][]*_prof_node
+	calls    int64
+	incl     time.Duration
+	excl     time.Duration
+	allocs   int64 //inclusive
+	xallocs  int64 //exclusive
+}
+
+//a command being evaluated
+type _prof_open struct {
+	node         *_prof_node //nil for a quote run in place of its caller
+	start        time.Time
+	allocs       int64
+	child_time   time.Duration
+	child_allocs int64
+}
+
+type _profiler struct {
+	start    time.Time
+	base     int //frames below base were pushed before profiling started
+	root     _prof_node
+	open     []_prof_open
+	sample   []metrics.Sample
+	overhead int64 //allocations made by the profiler
+}
+
+//Start recording a profile of the commands vm evaluates, discarding any
+//profile already being recorded. The profile includes the commands vm
+//evaluates in the programs it runs and the Aliens it invokes, but not those
+//of the VMs it spawns.
+//Call before running vm or from its goroutine, such as from an Alien.
+func (vm *VM) StartProfile() {
+	vm._sanity("start profiling")
+	p := &_profiler{
+		start:  time.Now(),
+		base:   len(vm.frames),
+		sample: []metrics.Sample{{Name: _allocs_metric}},
+	}
+	p.root.name = "<root>"
+	vm.prof = p
+}
+
+//Stop recording the profile of vm and return it, or nil if vm was not
+//profiling. Commands still being evaluated are recorded as if they returned.
+//Call from the same goroutine as StartProfile.
+func (vm *VM) StopProfile() *Profile {
+	p := vm.prof
+	if p == nil {
+		return nil
+	}
+	vm.prof = nil
+	for len(p.open) != 0 {
+		p._pop()
+	}
+	return &Profile{p.start, time.Since(p.start), &p.root}
+}
+
+//Reports whether vm is recording a profile
+func (vm *VM) Profiling() bool {
+	return vm.prof != nil
+}
+
+func (p *_profiler) _allocs() int64 {
+	metrics.Read(p.sample)
+	if p.sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int64(p.sample[0].Value.Uint64()) - p.overhead
+}
+
+//the name of a command without copying it if we can help it
+func _prof_name(w Word) []byte {
+	switch t := w.(type) {
+	case _iSymbol:
+		return []byte(t)
+	case _dSymbol:
+		return []byte(t)
+	case nil:
+		return []byte("<nil>")
+	}
+	return []byte(_summarize(_format1(w)))
+}
+
+func (n *_prof_node) _child(name []byte, pos SrcPos) *_prof_node {
+	if n.children == nil {
+		n.children = make(map[string][]*_prof_node)
+	}
+	same := n.children[string(name)]
+	for _, c := range same {
+		if c.pos.File == pos.File && c.pos.Line == pos.Line {
+			return c
+		}
+	}
+	c := &_prof_node{name: string(name), pos: pos, parent: n}
+	n.children[c.name] = append(same, c)
+	return c
+}
+
+//the innermost command being evaluated, -1 if there is none
+func (p *_profiler) _innermost(n int) int {
+	for n--; n >= 0 && p.open[n].node == nil; n-- {
+	}
+	return n
+}
+
+func (p *_profiler) _push(name Word, pos SrcPos) {
+	before := p._allocs()
+	if _, anon := name.(Quote); anon {
+		p.open = append(p.open, _prof_open{})
+		return
+	}
+	parent := &p.root
+	if i := p._innermost(len(p.open)); i >= 0 {
+		parent = p.open[i].node
+	}
+	p.open = append(p.open, _prof_open{node: parent._child(_prof_name(name), pos)})
+	p.overhead += p._allocs() - before
+	o := &p.open[len(p.open)-1]
+	o.allocs = before
+	o.start = time.Now()
+}
+
+func (p *_profiler) _pop() {
+	now, allocs := time.Now(), p._allocs()
+	n := len(p.open) - 1
+	o := p.open[n]
+	p.open = p.open[:n]
+	if o.node == nil {
+		return
+	}
+	incl, ialloc := now.Sub(o.start), allocs-o.allocs
+	o.node.calls++
+	o.node.incl += incl
+	o.node.excl += incl - o.child_time
+	o.node.allocs += ialloc
+	o.node.xallocs += ialloc - o.child_allocs
+	if i := p._innermost(n); i >= 0 {
+		p.open[i].child_time += incl
+		p.open[i].child_allocs += ialloc
+	}
+}
+
+//n is the number of frames before the pop
+func (p *_profiler) _pop_frame(n int) {
+	if n > p.base {
+		p._pop()
+	} else {
+		//a frame from before we started
+		p.base = n - 1
+	}
+}
+
+func (p *_profiler) _tail_frame(n int) {
+	switch {
+	case n-2 >= p.base:
+		p._tail()
+	case n-1 == p.base:
+		//the caller is from before we started, the callee moves down
+		p.base--
+	}
+}
+
+//the command on top tail called so it takes the place of the one below
+func (p *_profiler) _tail() {
+	n := len(p.open) - 1
+	if n < 1 {
+		return
+	}
+	callee := p.open[n]
+	p.open = p.open[:n]
+	if callee.node == nil {
+		//the caller goes on in the quote it returned
+		return
+	}
+	if p.open[n-1].node == nil {
+		//callee was already made a child of the command the quote is part of
+		p.open[n-1] = callee
+		return
+	}
+	//the caller is done, and the callee has done nothing worth counting
+	p._pop()
+	before := p._allocs()
+	parent := callee.node.parent.parent
+	callee.node = parent._child([]byte(callee.node.name), callee.node.pos)
+	p.overhead += p._allocs() - before
+	callee.start, callee.allocs = time.Now(), before
+	p.open = append(p.open, callee)
+}
+
+//A Profile is what a VM recorded between StartProfile and StopProfile.
+type Profile struct {
+	Start    time.Time
+	Duration time.Duration
+	root     *_prof_node
+}
+
+//What was recorded for one command, wherever it was called from
+type ProfileEntry struct {
+	Name      string
+	Calls     int64
+	Inclusive time.Duration //including the commands it invoked
+	Exclusive time.Duration //excluding the commands it invoked
+	//heap allocations counted while it was being evaluated, see Profile
+	InclusiveAllocs int64
+	ExclusiveAllocs int64
+}
+
+func (n *_prof_node) _walk(fn func(*_prof_node)) {
+	fn(n)
+	for _, same := range n.children {
+		for _, c := range same {
+			c._walk(fn)
+		}
+	}
+}
+
+//whether a command of the same name is somewhere above n, in which case the
+//inclusive totals of n are already in those of the command above
+func (n *_prof_node) _recursive() bool {
+	for a := n.parent; a != nil; a = a.parent {
+		if a.name == n.name {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns the totals of every command in the profile, those that took the
+//most time themselves first
+func (p *Profile) Entries() []ProfileEntry {
+	byname := make(map[string]*ProfileEntry)
+	p.root._walk(func(n *_prof_node) {
+		if n == p.root || n.calls == 0 {
+			return
+		}
+		e, ok := byname[n.name]
+		if !ok {
+			e = &ProfileEntry{Name: n.name}
+			byname[n.name] = e
+		}
+		e.Calls += n.calls
+		e.Exclusive += n.excl
+		e.ExclusiveAllocs += n.xallocs
+		if !n._recursive() {
+			e.Inclusive += n.incl
+			e.InclusiveAllocs += n.allocs
+		}
+	})
+	out := make([]ProfileEntry, 0, len(byname))
+	for _, e := range byname {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Exclusive != out[j].Exclusive {
+			return out[i].Exclusive > out[j].Exclusive
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+//Writes a table of the entries of the profile
+func (p *Profile) WriteSummary(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Profile of %v\n%10s %12s %12s %12s %12s  %s\n",
+		p.Duration, "calls", "inclusive", "exclusive", "incl allocs",
+		"excl allocs", "command")
+	if err != nil {
+		return err
+	}
+	for _, e := range p.Entries() {
+		_, err = fmt.Fprintf(w, "%10d %12v %12v %12d %12d  %s\n", e.Calls,
+			e.Inclusive, e.Exclusive, e.InclusiveAllocs, e.ExclusiveAllocs,
+			e.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Writes the profile as a gzipped protocol buffer in the format of
+//runtime/pprof, so that it can be read by go tool pprof. Each command is a
+//function named after it in the file it was called from.
+func (p *Profile) WritePprof(w io.Writer) error {
+	var b _pprof
+	b.strings = map[string]int64{"": 0}
+	b.strtab = []string{""}
+	b.funcs = make(map[[2]string]uint64)
+	b.locs = make(map[_pprof_loc]uint64)
+	for _, t := range [][2]string{{"calls", "count"},
+		{"time", "nanoseconds"}, {"allocs", "count"}} {
+		b.msg(1, b.value_type(t[0], t[1]))
+	}
+	p.root._walk(func(n *_prof_node) {
+		if n == p.root || n.calls == 0 {
+			return
+		}
+		var ids []uint64
+		for a := n; a != p.root; a = a.parent {
+			ids = append(ids, b.location(a))
+		}
+		var s _pbuf
+		s.packed(1, ids)
+		s.packed(2, []uint64{uint64(n.calls), uint64(n.excl),
+			uint64(n.xallocs)})
+		b.msg(2, s)
+	})
+	b.buf = append(b.buf, b.rest...)
+	for _, str := range b.strtab {
+		b.bytes(6, []byte(str))
+	}
+	b.int(9, uint64(p.Start.UnixNano()))
+	b.int(10, uint64(p.Duration))
+	b.msg(11, b.value_type("time", "nanoseconds"))
+	b.int(12, 1)
+	b.int(14, uint64(b.str("time")))
+	z := gzip.NewWriter(w)
+	if _, err := z.Write(b.buf); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+//just enough of the protocol buffer wire format to write a profile
+
+type _pbuf struct {
+	buf []byte
+}
+
+func (b *_pbuf) varint(v uint64) {
+	for v >= 0x80 {
+		b.buf = append(b.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	b.buf = append(b.buf, byte(v))
+}
+
+func (b *_pbuf) int(field int, v uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(v)
+}
+
+func (b *_pbuf) bytes(field int, v []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(v)))
+	b.buf = append(b.buf, v...)
+}
+
+func (b *_pbuf) msg(field int, m _pbuf) {
+	b.bytes(field, m.buf)
+}
+
+func (b *_pbuf) packed(field int, vs []uint64) {
+	var m _pbuf
+	for _, v := range vs {
+		m.varint(v)
+	}
+	b.msg(field, m)
+}
+
+type _pprof_loc struct {
+	fn   uint64
+	line int
+}
+
+//samples are written to buf as they are made, the locations and functions
+//they need to rest
+type _pprof struct {
+	_pbuf
+	rest    []byte
+	strings map[string]int64
+	strtab  []string
+	funcs   map[[2]string]uint64
+	locs    map[_pprof_loc]uint64
+}
+
+func (b *_pprof) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	i := int64(len(b.strtab))
+	b.strings[s] = i
+	b.strtab = append(b.strtab, s)
+	return i
+}
+
+func (b *_pprof) value_type(typ, unit string) _pbuf {
+	var m _pbuf
+	m.int(1, uint64(b.str(typ)))
+	m.int(2, uint64(b.str(unit)))
+	return m
+}
+
+//pprof drops whatever is between angle brackets in the name of a function, as
+//it would the arguments of a C++ template, so <program> would have no name
+func _pprof_name(name string) string {
+	if len(name) > 2 && name[0] == '<' && name[len(name)-1] == '>' {
+		return "(" + name[1:len(name)-1] + ")"
+	}
+	return name
+}
+
+func (b *_pprof) location(n *_prof_node) uint64 {
+	key := [2]string{n.name, n.pos.File}
+	fn, ok := b.funcs[key]
+	if !ok {
+		fn = uint64(len(b.funcs) + 1)
+		b.funcs[key] = fn
+		name := _pprof_name(n.name)
+		var m _pbuf
+		m.int(1, fn)
+		m.int(2, uint64(b.str(name)))
+		m.int(3, uint64(b.str(name)))
+		m.int(4, uint64(b.str(n.pos.File)))
+		r := _pbuf{b.rest}
+		r.msg(5, m)
+		b.rest = r.buf
+	}
+	lk := _pprof_loc{fn, n.pos.Line}
+	id, ok := b.locs[lk]
+	if !ok {
+		id = uint64(len(b.locs) + 1)
+		b.locs[lk] = id
+		var line, m _pbuf
+		line.int(1, fn)
+		line.int(2, uint64(n.pos.Line))
+		m.int(1, id)
+		m.msg(4, line)
+		r := _pbuf{b.rest}
+		r.msg(4, m)
+		b.rest = r.buf
+	}
+	return id
+}
@@ -0,0 +1,101 @@
+package gelo_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+const _profile_src = `set! n 0
+set! f {
+	set! n [+ $n 1]
+	if [< $n $max] then { f }
+}
+f
+`
+
+func _profile(t *testing.T, max int) *gelo.Profile {
+	t.Helper()
+	vm := _new_vm()
+	vm.Register("max", max)
+	vm.StartProfile()
+	if _, err := vm.Run("profile.gel", strings.NewReader(_profile_src),
+		nil); err != nil {
+		t.Fatal(err)
+	}
+	return vm.StopProfile()
+}
+
+func TestProfileTailCalls(t *testing.T) {
+	for _, max := range []int{1, 3, 10} {
+		calls := make(map[string]int64)
+		for _, e := range _profile(t, max).Entries() {
+			calls[e.Name] = e.Calls
+		}
+		if calls["f"] != int64(max) || calls["if"] != int64(max) ||
+			calls["<program>"] != 1 {
+			t.Errorf("f called %d times profiled as %v", max, calls)
+		}
+		//the branch of the if is part of the if
+		for name := range calls {
+			if strings.HasPrefix(name, "f ") {
+				t.Errorf("the branch %q was profiled as a command", name)
+			}
+		}
+	}
+}
+
+func TestProfileSummary(t *testing.T) {
+	var out bytes.Buffer
+	if err := _profile(t, 3).WriteSummary(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"calls", "<program>", "set!"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary has no %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestProfilePprof(t *testing.T) {
+	var out bytes.Buffer
+	if err := _profile(t, 3).WritePprof(&out); err != nil {
+		t.Fatal(err)
+	}
+	z, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//pprof would drop a name in angle brackets
+	for _, want := range []string{"(program)", "profile.gel", "nanoseconds"} {
+		if !bytes.Contains(pb, []byte(want)) {
+			t.Errorf("pprof profile has no %q", want)
+		}
+	}
+	if bytes.Contains(pb, []byte("<program>")) {
+		t.Error("pprof profile names a function <program>")
+	}
+}
+
+func TestProfileStop(t *testing.T) {
+	vm := _new_vm()
+	if vm.StopProfile() != nil {
+		t.Error("StopProfile returned a profile without StartProfile")
+	}
+	vm.StartProfile()
+	if !vm.Profiling() {
+		t.Error("not profiling after StartProfile")
+	}
+	vm.StopProfile()
+	if vm.Profiling() {
+		t.Error("still profiling after StopProfile")
+	}
+}
@@ -0,0 +1,94 @@
+package gelo
+
+import "sync/atomic"
+
+//Quotas bound the resources a script may consume. They are enforced by the
+//builtins that can produce large values or spawn VMs, not by every operation
+//a script could perform, so they are a defense against runaway or hostile
+//scripts rather than an exact accounting. A zero field means no limit.
+//
+//Only make-list and range check ListLength, and only join, re-replace and
+//re-replace-by check SymbolSize, before or while building their result. A
+//list or symbol grown a step at a time, as by List @l $x in a loop, is not
+//checked against either; bound such loops with a step limit.
+type Quotas struct {
+	ListLength int64 //longest list a builtin may build
+	SymbolSize int64 //largest symbol, in bytes, a builtin may build
+	Children   int64 //most VMs spawned with go that may be alive at once
+	NsDepth    int64 //most namespaces that may be forked in a VM
+}
+
+//Quotas are shared by a VM and the VMs it spawns, like step budgets, so the
+//children spawned by go count against the VM that set the quotas.
+type _quotas struct {
+	Quotas
+	live int64 //number of live VMs spawned by go
+}
+
+//Set the quotas of vm and any VMs it spawns from now on, replacing the
+//quotas vm inherited from its parent, if any.
+func (vm *VM) SetQuotas(q Quotas) {
+	vm._sanity("set quotas")
+	vm.quotas = &_quotas{Quotas: q}
+}
+
+//Returns the quotas in effect for vm
+func (vm *VM) Quotas() Quotas {
+	if vm.quotas == nil {
+		return Quotas{}
+	}
+	return vm.quotas.Quotas
+}
+
+//Raise a runtime error of kind ErrKindListQuota if a list of n items would
+//exceed the quota of the VM. Aliens that build lists whose length is not
+//bounded by their arguments should call this before building them.
+func (p *api) CheckListLength(n int64) {
+	q := p.vm.quotas
+	if q != nil && q.ListLength > 0 && n > q.ListLength {
+		quotaExceeded(p.vm, ErrKindListQuota, "List length quota exceeded:", n,
+			"items requested, limit", q.ListLength)
+	}
+}
+
+//Raise a runtime error of kind ErrKindSymbolQuota if a symbol of n bytes
+//would exceed the quota of the VM.
+func (p *api) CheckSymbolSize(n int64) {
+	q := p.vm.quotas
+	if q != nil && q.SymbolSize > 0 && n > q.SymbolSize {
+		quotaExceeded(p.vm, ErrKindSymbolQuota, "Symbol size quota exceeded:",
+			n, "bytes requested, limit", q.SymbolSize)
+	}
+}
+
+//called before a VM is spawned by go, the VM must call _release_child when
+//it is done
+func (vm *VM) _acquire_child() {
+	q := vm.quotas
+	if q == nil || q.Children <= 0 {
+		return
+	}
+	if atomic.AddInt64(&q.live, 1) > q.Children {
+		atomic.AddInt64(&q.live, -1)
+		quotaExceeded(vm, ErrKindChildQuota, "Child VM quota exceeded:",
+			q.Children, "spawned VMs are alive")
+	}
+}
+
+func (q *_quotas) _release_child() {
+	if q != nil && q.Children > 0 {
+		atomic.AddInt64(&q.live, -1)
+	}
+}
+
+//called before the namespace of vm is forked
+func (vm *VM) _check_ns_depth() {
+	q := vm.quotas
+	if q == nil || q.NsDepth <= 0 {
+		return
+	}
+	if int64(vm.Ns.LocalDepth()) > q.NsDepth {
+		quotaExceeded(vm, ErrKindDepthQuota, "Namespace depth quota exceeded:",
+			q.NsDepth, "forks")
+	}
+}
@@ -0,0 +1,78 @@
+package gelo_test
+
+import (
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+func TestListLengthQuota(t *testing.T) {
+	vm := _new_vm()
+	vm.SetQuotas(gelo.Quotas{ListLength: 10})
+	if got := _eval(t, vm, "range 0 to 10"); got != "{0 1 2 3 4 5 6 7 8 9}" {
+		t.Errorf("range 0 to 10 = %s", got)
+	}
+	_eval_err(t, vm, "range 0 to 11", gelo.ErrKindListQuota)
+	_eval_err(t, vm, "make-list 11 long with x", gelo.ErrKindListQuota)
+}
+
+func TestSymbolSizeQuota(t *testing.T) {
+	vm := _new_vm()
+	vm.SetQuotas(gelo.Quotas{SymbolSize: 8})
+	if got := _eval(t, vm, "join {ab cd} with -"); got != "ab-cd" {
+		t.Errorf("join = %s", got)
+	}
+	_eval_err(t, vm, "join {abcd efgh} with -", gelo.ErrKindSymbolQuota)
+	_eval_err(t, vm, "re-replace [Re a] aaaa bbb", gelo.ErrKindSymbolQuota)
+	_eval_err(t, vm, "re-replace-by [Re a] aaaa { value bbb }",
+		gelo.ErrKindSymbolQuota)
+	got := _eval(t, vm, "re-replace [Re (a)(b)] xabyab {$2$1}")
+	if got != "xbayba" {
+		t.Errorf("re-replace = %s", got)
+	}
+	if got := _eval(t, vm, "re-replace [Re b*] abc -"); got != "-a-c-" {
+		t.Errorf("re-replace empty matches = %s", got)
+	}
+}
+
+//re-replace-by stops at the quota as it builds its result, rather than
+//making every replacement first
+func TestSymbolSizeQuotaReplace(t *testing.T) {
+	vm := _new_vm()
+	vm.SetQuotas(gelo.Quotas{SymbolSize: 1 << 10})
+	n := 0
+	vm.Register("grow", func(vm *gelo.VM, args *gelo.List, ac uint) gelo.Word {
+		n++
+		return gelo.StrToSym("xxxxxxxx")
+	})
+	//999 bytes, each replacement adds 7
+	_eval_err(t, vm, "re-replace-by [Re a] [make-list 500 long with a] grow",
+		gelo.ErrKindSymbolQuota)
+	if n != 4 {
+		t.Errorf("%d replacements made, want 4", n)
+	}
+}
+
+func TestChildQuota(t *testing.T) {
+	vm := _new_vm()
+	vm.SetQuotas(gelo.Quotas{Children: 1})
+	_eval(t, vm, "set! c [Chan]; go read! $c")
+	_eval_err(t, vm, "go read! $c", gelo.ErrKindChildQuota)
+	//let the first child finish
+	_eval(t, vm, "write! $c done")
+}
+
+func TestNsDepthQuota(t *testing.T) {
+	vm := _new_vm()
+	vm.SetQuotas(gelo.Quotas{NsDepth: 2})
+	_eval(t, vm, "ns fork; ns fork; ns unfork; ns unfork")
+	_eval_err(t, vm, "ns fork; ns fork; ns fork", gelo.ErrKindDepthQuota)
+}
+
+func TestQuotasInherited(t *testing.T) {
+	vm := _new_vm()
+	vm.SetQuotas(gelo.Quotas{ListLength: 3})
+	if got := _eval(t, vm, "safe-eval range 0 to 5"); got == "{0 1 2 3 4}" {
+		t.Errorf("safe-eval escaped the list length quota")
+	}
+}
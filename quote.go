@@ -2,17 +2,17 @@ package gelo
 
 import "bytes"
 
-var Noop = &protected_quote{&quote{false, nil, []byte("")}}
+var Noop = &protected_quote{&quote{false, nil, []byte(""), SrcPos{}, nil}}
 
 func NewQuoteFrom(w Word) Quote {
 	if q, ok := w.(Quote); ok {
 		return q
 	}
-	return &protected_quote{&quote{false, nil, dup(w.Ser().Bytes())}}
+	return &protected_quote{&quote{false, nil, dup(w.Ser().Bytes()), SrcPos{}, nil}}
 }
 
 func NewQuoteFromGo(t []byte) Quote {
-	return &protected_quote{&quote{false, nil, dup(t)}}
+	return &protected_quote{&quote{false, nil, dup(t), SrcPos{}, nil}}
 }
 
 //This is a black magic function
@@ -20,7 +20,7 @@ func build_quote_from_list(args *List) *quote {
 	if args == nil {
 		return Noop.unprotect()
 	}
-	src := newBuf(0)
+	src, list := newBuf(0), args
 	var chead, ctail *sNode
 	for ; args != nil; args = args.Next {
 		src.Write(args.Value.Ser().Bytes())
@@ -29,14 +29,21 @@ func build_quote_from_list(args *List) *quote {
 			//this is safe because when the vm rewrites it will
 			//just blindly fill literals, so it doesn't matter what
 			//the actual type is
-			ctail.next = &sNode{synLiteral, args.Value, nil}
+			ctail.next = &sNode{synLiteral, args.Value, nil, SrcPos{}}
 			ctail = ctail.next
 		} else {
-			chead = &sNode{synLiteral, args.Value, nil}
+			chead = &sNode{synLiteral, args.Value, nil, SrcPos{}}
 			ctail = chead
 		}
 	}
-	return &quote{false, &command{chead, nil}, src.Bytes()}
+	//rather than compile the tree we just built, we splice the list back
+	//into place, which costs the same no matter how long it is
+	code := &_code{[]_instr{
+		{op: op_mark}, {op: op_push, w: list}, {op: op_splice},
+		{op: op_tail, src: chead},
+	}}
+	return &quote{false, &command{chead, nil, SrcPos{}}, src.Bytes(), SrcPos{},
+		code}
 }
 
 //we ONLY call this in very specific situations where we KNOW the quote does not
@@ -51,7 +58,7 @@ func force_synerr(vm *VM, q Quote) (ret *ErrSyntax) {
 		}
 		ret = x.(*ErrSyntax)
 	}()
-	parse(newBufFrom(Q.source))
+	parse(newBufFrom(Q.source), Q.pos)
 	return
 }
 
@@ -78,16 +85,18 @@ func (q *quote) fcode() (code *command, ok bool) {
 				panic(x)
 			}
 		}()
-		q.code = parse(newBufFrom(q.source))
+		q.code = parse(newBufFrom(q.source), q.pos)
 		code, ok = q.code, true
 	}
 	return
 }
 
 type quote struct {
-	literal bool //false until proven otherwise
-	code    *command
-	source  []byte
+	literal  bool //false until proven otherwise
+	code     *command
+	source   []byte
+	pos      SrcPos //where source begins, if it was parsed from a file
+	compiled *_code //compiled from code when first invoked
 }
 
 func (q *quote) unprotect() *quote {
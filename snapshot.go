@@ -0,0 +1,395 @@
+package gelo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+//A snapshot holds the namespaces of a VM, from the outermost namespace the VM
+//owns to its current namespace, in a binary format:
+//
+//	"GELOSNAP" version namespaces namespace...
+//
+//where each namespace is the number of its bindings followed by each binding,
+//sorted by name, as a name and a word. Numbers are unsigned varints and
+//names, symbols and other byte strings are a length followed by the bytes. A
+//word is a tag byte followed by its contents, as written by _snap_writer.word.
+//
+//A VM spawned by another does not own the namespaces of its parent, so they
+//are not in its snapshots.
+
+const (
+	_snap_magic   = "GELOSNAP"
+	_snap_version = 1
+)
+
+const (
+	snap_isym   byte = iota //an interned symbol
+	snap_dsym               //a symbol that was not interned
+	snap_number             //the serialization of the number
+	snap_true               //nothing follows either bool
+	snap_false
+	snap_list    //the length of the list then its items
+	snap_dict    //the number of entries then each key and value, by key
+	snap_quote   //source, file, line, column
+	snap_foreign //the bytes a SnapshotCodec encoded the word as
+	snap_decimal //the serialization of the decimal then its rounding mode
+)
+
+//A SnapshotCodec lets a host save the words that a snapshot cannot, such as
+//Aliens, Ports and Words of types defined by the host.
+type SnapshotCodec interface {
+	//Returns the bytes to save for w, or ok false if w cannot be saved.
+	//A variable bound to a word that cannot be saved is left out of the
+	//snapshot, but a list or dict that contains one cannot be saved at all.
+	Encode(w Word) (b []byte, ok bool, err error)
+	//Returns the word that Encode saved as b
+	Decode(b []byte) (Word, error)
+}
+
+//Set the codec used by vm to save and restore the words that snapshots
+//cannot, nil to leave such variables out of the snapshots of vm, which is the
+//default. This leaves out the Aliens registered with vm and any Ports, so a
+//snapshot should be restored in a VM that has registered the same Aliens.
+func (vm *VM) SetSnapshotCodec(c SnapshotCodec) {
+	vm._sanity("set a snapshot codec")
+	vm.codec = c
+}
+
+//Returns the namespaces of vm and their contents as a snapshot that can be
+//restored by Restore.
+//Call when vm is not running or from its goroutine, such as from an Alien.
+func (vm *VM) Snapshot() (out []byte, err error) {
+	vm._sanity("take a snapshot")
+	var chain []*namespace
+	for ns := vm.cns; ns != vm.top && ns != nil; ns = ns.up {
+		chain = append(chain, ns)
+	}
+	w := &_snap_writer{codec: vm.codec, seen: make(map[interface{}]bool)}
+	w.buf.WriteString(_snap_magic)
+	w.uint(_snap_version)
+	w.uint(uint64(len(chain)))
+	for i := len(chain) - 1; i >= 0; i-- {
+		if err = w.namespace(chain[i]); err != nil {
+			return nil, err
+		}
+	}
+	return w.buf.Bytes(), nil
+}
+
+//Restores the namespaces of vm from a snapshot made by Snapshot. The
+//variables of the outermost namespace of the snapshot are set in the
+//outermost namespace vm owns, which keeps any variables, such as the Aliens
+//registered with vm, that the snapshot does not replace. Any namespaces vm
+//has forked are discarded and replaced by those in the snapshot. If the
+//snapshot cannot be read vm is left as it was.
+//Do not call while vm is running.
+func (vm *VM) Restore(snap []byte) (err error) {
+	vm._sanity("restore a snapshot")
+	r := &_snap_reader{buf: snap, codec: vm.codec}
+	defer func() {
+		if x := recover(); x != nil {
+			e, ok := x.(_snap_error)
+			if !ok {
+				panic(x)
+			}
+			err = e.error
+		}
+	}()
+	if len(snap) < len(_snap_magic) ||
+		string(snap[:len(_snap_magic)]) != _snap_magic {
+		return errors.New("gelo: not a snapshot")
+	}
+	r.pos = len(_snap_magic)
+	if v := r.uint(); v != _snap_version {
+		return fmt.Errorf("gelo: snapshot version %d not supported", v)
+	}
+	count := r.uint()
+	if count == 0 {
+		r.fail("no namespaces")
+	}
+	dicts := make([]map[string]Word, 0, count)
+	for i := uint64(0); i < count; i++ {
+		dicts = append(dicts, r.namespace())
+	}
+	if r.pos != len(r.buf) {
+		r.fail("trailing data")
+	}
+	//everything was read, now change vm
+	outer := vm._outer_ns()
+	for k, v := range dicts[0] {
+		outer.set(StrToSym(k), v)
+	}
+	vm.cns = outer
+	for _, d := range dicts[1:] {
+		vm.cns = newNamespaceFrom(vm.cns, &Dict{rep: d})
+	}
+	return nil
+}
+
+type _snap_writer struct {
+	buf   bytes.Buffer
+	codec SnapshotCodec
+	seen  map[interface{}]bool //the lists and dicts being written
+}
+
+//cannot be saved, not an error if it is bound to a variable
+var _snap_unsaveable = errors.New("gelo: word cannot be saved in a snapshot")
+
+func (w *_snap_writer) uint(n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func (w *_snap_writer) bytes(b []byte) {
+	w.uint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *_snap_writer) namespace(ns *namespace) error {
+	ns.mux.RLock()
+	defer ns.mux.RUnlock()
+	keys := make([]string, 0, len(ns.dict.rep))
+	for k := range ns.dict.rep {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	//encoded separately so unsaveable variables can be left out
+	var entries []byte
+	n := 0
+	for _, k := range keys {
+		mark := w.buf.Len()
+		w.bytes([]byte(k))
+		if err := w.word(ns.dict.rep[k]); err != nil {
+			w.buf.Truncate(mark)
+			if err == _snap_unsaveable {
+				continue
+			}
+			return fmt.Errorf("%v: in variable %s", err, k)
+		}
+		entries = append(entries, w.buf.Bytes()[mark:]...)
+		w.buf.Truncate(mark)
+		n++
+	}
+	w.uint(uint64(n))
+	w.buf.Write(entries)
+	return nil
+}
+
+func (w *_snap_writer) word(word Word) error {
+	switch t := word.(type) {
+	case _iSymbol:
+		w.buf.WriteByte(snap_isym)
+		w.bytes([]byte(t))
+	case _dSymbol:
+		w.buf.WriteByte(snap_dsym)
+		w.bytes([]byte(t))
+	case *Number:
+		w.buf.WriteByte(snap_number)
+		w.bytes(t.Ser().Bytes())
+	case *Decimal:
+		w.buf.WriteByte(snap_decimal)
+		w.bytes(t.Ser().Bytes())
+		w.uint(uint64(t.mode))
+	case Bool:
+		if t {
+			w.buf.WriteByte(snap_true)
+		} else {
+			w.buf.WriteByte(snap_false)
+		}
+	case *List:
+		if w.seen[t] {
+			return errors.New("gelo: cannot snapshot a list that contains itself")
+		}
+		w.seen[t] = true
+		defer delete(w.seen, t)
+		w.buf.WriteByte(snap_list)
+		w.uint(uint64(t.Len()))
+		for ; t != nil; t = t.Next {
+			if err := w.item(t.Value); err != nil {
+				return err
+			}
+		}
+	case *Dict:
+		if w.seen[t] {
+			return errors.New("gelo: cannot snapshot a dict that contains itself")
+		}
+		w.seen[t] = true
+		defer delete(w.seen, t)
+		keys := make([]string, 0, len(t.rep))
+		for k := range t.rep {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.buf.WriteByte(snap_dict)
+		w.uint(uint64(len(keys)))
+		for _, k := range keys {
+			w.bytes([]byte(k))
+			if err := w.item(t.rep[k]); err != nil {
+				return err
+			}
+		}
+	case Quote:
+		q := t.unprotect()
+		w.buf.WriteByte(snap_quote)
+		w.bytes(q.source)
+		w.bytes([]byte(q.pos.File))
+		w.uint(uint64(q.pos.Line))
+		w.uint(uint64(q.pos.Column))
+	default:
+		if w.codec == nil {
+			return _snap_unsaveable
+		}
+		b, ok, err := w.codec.Encode(word)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return _snap_unsaveable
+		}
+		w.buf.WriteByte(snap_foreign)
+		w.bytes(b)
+	}
+	return nil
+}
+
+//an item of a list or dict must be saved
+func (w *_snap_writer) item(word Word) error {
+	err := w.word(word)
+	if err == _snap_unsaveable {
+		return fmt.Errorf("%v: %s", err, word.Type())
+	}
+	return err
+}
+
+type _snap_error struct {
+	error
+}
+
+type _snap_reader struct {
+	buf   []byte
+	pos   int
+	codec SnapshotCodec
+}
+
+func (r *_snap_reader) fail(s ...interface{}) {
+	panic(_snap_error{fmt.Errorf("gelo: corrupt snapshot at byte %d: %s",
+		r.pos, fmt.Sprint(s...))})
+}
+
+func (r *_snap_reader) uint() uint64 {
+	n, size := binary.Uvarint(r.buf[r.pos:])
+	if size <= 0 {
+		r.fail("bad number")
+	}
+	r.pos += size
+	return n
+}
+
+func (r *_snap_reader) int() int {
+	n := r.uint()
+	if n > math.MaxInt32 {
+		r.fail("number out of range")
+	}
+	return int(n)
+}
+
+func (r *_snap_reader) bytes() []byte {
+	n := r.uint()
+	if n > uint64(len(r.buf)-r.pos) {
+		r.fail("truncated")
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return dup(b)
+}
+
+//a count of things, each of which takes at least a byte
+func (r *_snap_reader) count() int {
+	n := r.uint()
+	if n > uint64(len(r.buf)-r.pos) {
+		r.fail("truncated")
+	}
+	return int(n)
+}
+
+func (r *_snap_reader) namespace() map[string]Word {
+	n := r.count()
+	d := make(map[string]Word, n)
+	for i := 0; i < n; i++ {
+		k := string(r.bytes())
+		d[k] = r.word()
+	}
+	return d
+}
+
+func (r *_snap_reader) word() Word {
+	if r.pos >= len(r.buf) {
+		r.fail("truncated")
+	}
+	tag := r.buf[r.pos]
+	r.pos++
+	switch tag {
+	case snap_isym:
+		return intern(r.bytes())
+	case snap_dsym:
+		return BytesToSym(r.bytes())
+	case snap_number:
+		n, ok := NewNumberFromBytes(r.bytes())
+		if !ok {
+			r.fail("bad number")
+		}
+		return n
+	case snap_decimal:
+		d, ok := NewDecimalFromString(string(r.bytes()))
+		mode := r.int()
+		if !ok || mode >= len(_rounding_modes) {
+			r.fail("bad decimal")
+		}
+		d.mode = RoundingMode(mode)
+		return d
+	case snap_true:
+		return True
+	case snap_false:
+		return False
+	case snap_list:
+		n := r.count()
+		items := make([]Word, n)
+		for i := range items {
+			items[i] = r.word()
+		}
+		return NewListFrom(items)
+	case snap_dict:
+		n := r.count()
+		d := make(map[string]Word, n)
+		for i := 0; i < n; i++ {
+			k := string(r.bytes())
+			d[k] = r.word()
+		}
+		return &Dict{rep: d}
+	case snap_quote:
+		src := r.bytes()
+		pos := SrcPos{string(r.bytes()), r.int(), 0}
+		pos.Column = r.int()
+		if len(src) == 0 {
+			return Noop
+		}
+		return &protected_quote{&quote{false, nil, src, pos, nil}}
+	case snap_foreign:
+		b := r.bytes()
+		if r.codec == nil {
+			r.fail("no SnapshotCodec to decode a word")
+		}
+		w, err := r.codec.Decode(b)
+		if err != nil {
+			panic(_snap_error{err})
+		}
+		return w
+	}
+	r.fail("unknown tag ", tag)
+	return nil
+}
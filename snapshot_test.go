@@ -0,0 +1,119 @@
+package gelo_test
+
+import (
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+const _snap_src = `set! big 18446744073709551616
+set! third [div 1 3]
+set! price [Decimal 1.50 rounding half-up]
+set! yes [= 1 1]
+set! l [List a 2 [List c]]
+set! d [Dict {{a 1} {b 2}}]
+set! inc {
+	+ $x 1
+}
+set! x 41
+set! alien [ns capture { value 1 }]
+`
+
+func _snapshot(t *testing.T, vm *gelo.VM) []byte {
+	t.Helper()
+	snap, err := vm.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snap
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	vm := _new_vm()
+	if _, err := vm.Run("snap.gel", strings.NewReader(_snap_src),
+		nil); err != nil {
+		t.Fatal(err)
+	}
+	_eval(t, vm, "ns fork; set! local here")
+	snap := _snapshot(t, vm)
+
+	restored := _new_vm()
+	if err := restored.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ src, want string }{
+		{"value $big", "18446744073709551616"},
+		{"+ $third $third", "2/3"},
+		{"round $price 1", "1.5"},
+		{"value $yes", "true"},
+		{"llength $l", "3"},
+		{"dict $d get b", "2"},
+		{"inc", "42"},
+		{"value $local", "here"},
+		{"ns unfork; set? local", "false"},
+	} {
+		if got := _eval(t, restored, c.src); got != c.want {
+			t.Errorf("%s = %s, want %s", c.src, got, c.want)
+		}
+	}
+	//the Alien could not be saved so it was left out
+	_eval_err(t, restored, "value $alien", gelo.ErrKindRuntime)
+	//the quote keeps where it was written
+	_eval(t, restored, "set! x a")
+	err := _eval_err(t, restored, "inc", gelo.ErrKindRuntime)
+	if !strings.HasPrefix(err.Error(), "snap.gel:8:2: ") {
+		t.Errorf("an error in a restored quote is at %v", err)
+	}
+}
+
+func TestSnapshotUnsaveable(t *testing.T) {
+	vm := _new_vm()
+	_eval(t, vm, "set! l [List [ns capture { value 1 }]]")
+	if _, err := vm.Snapshot(); err == nil {
+		t.Error("saved a list holding an Alien")
+	}
+}
+
+//saves every Port as a new channel
+type _chan_codec struct{}
+
+func (_chan_codec) Encode(w gelo.Word) ([]byte, bool, error) {
+	_, ok := w.(gelo.Port)
+	return []byte("chan"), ok, nil
+}
+
+func (_chan_codec) Decode(b []byte) (gelo.Word, error) {
+	return gelo.NewChan(), nil
+}
+
+func TestSnapshotCodec(t *testing.T) {
+	vm := _new_vm()
+	vm.SetSnapshotCodec(_chan_codec{})
+	_eval(t, vm, "set! c [Chan]; set! l [List $c]")
+	restored := _new_vm()
+	restored.SetSnapshotCodec(_chan_codec{})
+	if err := restored.Restore(_snapshot(t, vm)); err != nil {
+		t.Fatal(err)
+	}
+	if got := _eval(t, restored, "type-of $c"); got != "*CHAN*" {
+		t.Errorf("restored a %s", got)
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	vm := _new_vm()
+	_eval(t, vm, "set! x 1")
+	snap := _snapshot(t, vm)
+	restored := _new_vm()
+	_eval(t, restored, "set! x 2")
+	for _, bad := range [][]byte{nil, []byte("NOTASNAP"),
+		snap[:len(snap)-1], append(append([]byte{}, snap...), 0)} {
+		if err := restored.Restore(bad); err == nil {
+			t.Errorf("restored %q", bad)
+		}
+	}
+	if got := _eval(t, restored, "value $x"); got != "2" {
+		t.Errorf("a failed restore changed x to %s", got)
+	}
+}
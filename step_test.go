@@ -0,0 +1,32 @@
+package gelo_test
+
+import (
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+const _forever = "set! f { f }; f"
+
+func TestStepLimit(t *testing.T) {
+	vm := _new_vm()
+	vm.SetStepLimit(100)
+	_eval_err(t, vm, _forever, gelo.ErrKindStepLimit)
+	//the count starts again with each Do
+	for i := 0; i < 3; i++ {
+		_eval(t, vm, "repeat 30 { + 1 1 }")
+	}
+	vm.SetStepLimit(0)
+	_eval(t, vm, "repeat 1000 { + 1 1 }")
+}
+
+func TestStepLimitSafeEval(t *testing.T) {
+	vm := _new_vm()
+	if got := _eval(t, vm, "error-kind [safe-eval --steps 50 {"+_forever+
+		"}]"); got != gelo.ErrKindStepLimit {
+		t.Errorf("safe-eval --steps returned %s", got)
+	}
+	//the limit of the parent applies to what its children evaluate too
+	vm.SetStepLimit(100)
+	_eval_err(t, vm, "safe-eval {"+_forever+"}; + 1 1", gelo.ErrKindStepLimit)
+}
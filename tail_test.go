@@ -0,0 +1,48 @@
+package gelo_test
+
+import (
+	"strings"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+const _countdown = `set! even? {
+	if [= $n 0] then { value true } else {
+		set! n [- $n 1]
+		odd?
+	}
+}
+set! odd? {
+	if [= $n 0] then { value false } else {
+		set! n [- $n 1]
+		even?
+	}
+}
+`
+
+func TestTailCalls(t *testing.T) {
+	vm := _new_vm()
+	_eval(t, vm, _countdown)
+	if got := _eval(t, vm, "set! n 100001; even?"); got != "false" {
+		t.Errorf("even? 100001 = %s", got)
+	}
+}
+
+//the frames of the callers are replaced, so the stack stays as it was
+func TestTailCallFrames(t *testing.T) {
+	src := _countdown + `set! even? {
+	if [= $n 0] then { + 1 x } else { set! n [- $n 1]; odd? }
+}
+set! n 10000
+even?
+`
+	_, err := _new_vm().Run("tail.gel", strings.NewReader(src), nil)
+	r, ok := err.(*gelo.ErrRuntime)
+	if !ok {
+		t.Fatalf("expected a runtime error, got %v", err)
+	}
+	if len(r.Frames()) > 5 {
+		t.Errorf("%d frames after 10000 tail calls", len(r.Frames()))
+	}
+}
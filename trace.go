@@ -1,12 +1,18 @@
 package gelo
 
-import "fmt"
-import "sync"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
-type _trace byte
+type TraceKind byte
 
 const (
-	Alien_trace _trace = 1 << iota
+	Alien_trace TraceKind = 1 << iota
 	Runtime_trace
 	System_trace
 	Parser_trace
@@ -14,31 +20,274 @@ const (
 
 const All_traces = Alien_trace | Runtime_trace | System_trace | Parser_trace
 
-//global tracer
+func (k TraceKind) String() string {
+	switch k {
+	case Alien_trace:
+		return "alien"
+	case Runtime_trace:
+		return "runtime"
+	case System_trace:
+		return "system"
+	case Parser_trace:
+		return "parser"
+	}
+	return fmt.Sprintf("TraceKind(%d)", byte(k))
+}
+
+//the prefix of the events of each kind when formatted
+func (k TraceKind) _prefix() string {
+	switch k {
+	case Alien_trace:
+		return "X:"
+	case Runtime_trace:
+		return "R:"
+	case System_trace:
+		return "S:"
+	case Parser_trace:
+		return "P:"
+	}
+	return "?:"
+}
+
+//A TraceEvent is one thing that happened that a Tracer was told of. Every
+//event has a Kind, a Time and a Message. Runtime and alien events also say
+//which command was being evaluated when they happened. The runtime events
+//with the messages "invoking" and "returned" are sent as each command
+//starts and finishes, and those sent as it finishes have its Result and how
+//long it took.
+type TraceEvent struct {
+	Kind     TraceKind
+	VM       uint32 //the id of the VM, 0 if the event is not about one
+	Command  string //the command being evaluated, abbreviated if long
+	Args     *List  //the arguments of the command being evaluated
+	Result   Word
+	Duration time.Duration
+	Time     time.Time
+	Message  string
+}
+
+//Formats e as the Ports given to SetTracer receive it
+func (e *TraceEvent) String() string {
+	buf := newBuf(0)
+	buf.WriteString(e.Kind._prefix())
+	switch {
+	case e.Message == "invoking" && e.Kind == Runtime_trace:
+		buf.WriteString(" invoking ")
+		buf.WriteString(e.Command)
+		if e.Args != nil {
+			buf.WriteString(" ")
+			buf.WriteString(_summarize(_format1(e.Args)))
+		}
+	case e.Message == "returned" && e.Kind == Runtime_trace:
+		buf.WriteString(" ")
+		buf.WriteString(e.Command)
+		buf.WriteString(" returned ")
+		buf.Write(_format1(e.Result))
+		buf.WriteString(" in ")
+		buf.WriteString(e.Duration.String())
+	default:
+		buf.WriteString(" ")
+		buf.WriteString(e.Message)
+	}
+	return buf.String()
+}
+
+//A Tracer is told of the events of the kinds that are on. Trace is called
+//from the goroutine of the VM the event is about, so it may be called from
+//many goroutines at once. It must not change the event or its Args or
+//Result, and must copy any it wishes to keep.
+type Tracer interface {
+	Trace(e *TraceEvent)
+}
+
+type _port_tracer struct {
+	p Port
+}
+
+func (t _port_tracer) Trace(e *TraceEvent) {
+	t.p.Send(StrToSym(e.String()))
+}
+
+//Returns a Tracer that sends each event to p formatted as a symbol
+func PortTracer(p Port) Tracer {
+	return _port_tracer{p}
+}
+
+//Which events a Tracer returned by FilterTracer passes on. Every condition
+//that is set must hold.
+type TraceFilter struct {
+	Kinds   TraceKind      //the kinds of events to pass, 0 for all
+	VMs     []uint32       //the ids of the VMs to pass the events of
+	Command *regexp.Regexp //matched against the Command of each event
+}
+
+type _filter_tracer struct {
+	t Tracer
+	f TraceFilter
+}
+
+func (t *_filter_tracer) Trace(e *TraceEvent) {
+	f := &t.f
+	if f.Kinds != 0 && f.Kinds&e.Kind == 0 {
+		return
+	}
+	if len(f.VMs) != 0 {
+		found := false
+		for _, id := range f.VMs {
+			if id == e.VM {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+	if f.Command != nil && !f.Command.MatchString(e.Command) {
+		return
+	}
+	t.t.Trace(e)
+}
+
+//Returns a Tracer that passes the events that f allows on to t
+func FilterTracer(t Tracer, f TraceFilter) Tracer {
+	return &_filter_tracer{t, f}
+}
+
+//The tracer and trace level of the VMs that have not been given their own.
+//VMs spawned by such a VM follow the globals too.
 var _tracer_mutex sync.RWMutex
-var _the_tracer Port
-var _level _trace
+var _the_tracer Tracer
+var _level uint32 //a TraceKind, read without locking
 
-func SetTracer(p Port) Port {
-	old := _the_tracer
+//Set the default Tracer that is told of events, nil for none. Returns the
+//previous default.
+func SetEventTracer(t Tracer) Tracer {
 	_tracer_mutex.Lock()
 	defer _tracer_mutex.Unlock()
-	_the_tracer = p
+	old := _the_tracer
+	_the_tracer = t
 	return old
 }
 
-func TraceOn(lvl _trace) _trace {
+//Send the events to p formatted as symbols by default, nil for nowhere.
+//Returns the previous default Port, or nil if the previous default Tracer was
+//not set by SetTracer.
+func SetTracer(p Port) Port {
+	old, _ := SetEventTracer(_port_tracer_of(p)).(_port_tracer)
+	return old.p
+}
+
+//Turn on the kinds of traces in lvl by default, returns the new default
+func TraceOn(lvl TraceKind) TraceKind {
 	_tracer_mutex.Lock()
 	defer _tracer_mutex.Unlock()
-	_level |= lvl
-	return _level
+	l := TraceKind(atomic.LoadUint32(&_level)) | lvl
+	atomic.StoreUint32(&_level, uint32(l))
+	return l
 }
 
-func TraceOff(lvl _trace) _trace {
+//Turn off the kinds of traces in lvl by default, returns the new default
+func TraceOff(lvl TraceKind) TraceKind {
 	_tracer_mutex.Lock()
 	defer _tracer_mutex.Unlock()
-	_level &^= lvl
-	return _level
+	l := TraceKind(atomic.LoadUint32(&_level)) &^ lvl
+	atomic.StoreUint32(&_level, uint32(l))
+	return l
+}
+
+func _port_tracer_of(p Port) Tracer {
+	if p == nil {
+		return nil
+	}
+	return PortTracer(p)
+}
+
+func _default_trace() *_trace_cfg {
+	_tracer_mutex.RLock()
+	defer _tracer_mutex.RUnlock()
+	return &_trace_cfg{_the_tracer, TraceKind(atomic.LoadUint32(&_level))}
+}
+
+//The tracing of a VM that has been given its own. It is never changed once
+//made so it can be read while the VM runs, changing the tracing of a VM
+//replaces it.
+type _trace_cfg struct {
+	tracer Tracer
+	level  TraceKind
+}
+
+//serializes changes to the tracing of all VMs, which are rare
+var _vm_trace_mutex sync.Mutex
+
+//nil if vm follows the globals
+func (vm *VM) _trace_cfg() *_trace_cfg {
+	if vm == nil {
+		return nil
+	}
+	c, _ := vm.trace.Load().(*_trace_cfg)
+	return c
+}
+
+func (vm *VM) _change_trace(change func(c *_trace_cfg)) {
+	_vm_trace_mutex.Lock()
+	defer _vm_trace_mutex.Unlock()
+	c := vm._trace_cfg()
+	if c == nil {
+		c = _default_trace()
+	} else {
+		dup := *c
+		c = &dup
+	}
+	change(c)
+	vm.trace.Store(c)
+}
+
+//Set the Tracer of vm, nil for none, and of the VMs it spawns from now on, in
+//place of the default. Safe to call from any goroutine.
+func (vm *VM) SetEventTracer(t Tracer) {
+	vm._change_trace(func(c *_trace_cfg) { c.tracer = t })
+}
+
+//Like SetEventTracer but sends the events to p formatted as symbols
+func (vm *VM) SetTracer(p Port) {
+	vm.SetEventTracer(_port_tracer_of(p))
+}
+
+//Turn on the kinds of traces in lvl for vm and the VMs it spawns from now
+//on, in place of the default. Returns the new trace level of vm. Safe to call
+//from any goroutine.
+func (vm *VM) TraceOn(lvl TraceKind) (out TraceKind) {
+	vm._change_trace(func(c *_trace_cfg) {
+		c.level |= lvl
+		out = c.level
+	})
+	return
+}
+
+//Like TraceOn but turns the kinds of traces in lvl off
+func (vm *VM) TraceOff(lvl TraceKind) (out TraceKind) {
+	vm._change_trace(func(c *_trace_cfg) {
+		c.level &^= lvl
+		out = c.level
+	})
+	return
+}
+
+//Have vm follow the default tracer and trace level again. VMs it has already
+//spawned keep what they inherited.
+func (vm *VM) DefaultTracing() {
+	_vm_trace_mutex.Lock()
+	defer _vm_trace_mutex.Unlock()
+	vm.trace.Store((*_trace_cfg)(nil))
+}
+
+//vm may be nil
+func (vm *VM) _tracing(req TraceKind) bool {
+	if c := vm._trace_cfg(); c != nil {
+		return c.level&req != 0
+	}
+	return TraceKind(atomic.LoadUint32(&_level))&req != 0
 }
 
 func DEBUG(all ...interface{}) {
@@ -87,31 +336,84 @@ func _format_trace(kind string, all []interface{}) *buffer {
 	return buf
 }
 
-func _tracer(req _trace, kind string, all []interface{}) {
-	_tracer_mutex.RLock()
-	defer _tracer_mutex.RUnlock()
-	if _level&req != 0 {
-		tr := _format_trace(kind, all).Symbol()
-		if _the_tracer != nil {
-			_the_tracer.Send(tr)
+func (vm *VM) _emit(e *TraceEvent) {
+	var t Tracer
+	if c := vm._trace_cfg(); c != nil {
+		t = c.tracer
+	} else {
+		_tracer_mutex.RLock()
+		t = _the_tracer
+		_tracer_mutex.RUnlock()
+	}
+	if t != nil {
+		t.Trace(e)
+	}
+}
+
+//vm may be nil
+func _tracer(vm *VM, req TraceKind, all []interface{}) {
+	if !vm._tracing(req) {
+		return
+	}
+	msg := strings.TrimPrefix(_format_trace("", all).String(), " ")
+	e := &TraceEvent{Kind: req, Time: time.Now(), Message: msg}
+	if vm != nil {
+		e.VM = vm.id
+		if req == Runtime_trace || req == Alien_trace {
+			vm._trace_command(e)
 		}
 	}
+	vm._emit(e)
+}
+
+//fills in the command being evaluated, only call from vm's goroutine
+func (vm *VM) _trace_command(e *TraceEvent) *_frame {
+	n := len(vm.frames)
+	if n == 0 {
+		return nil
+	}
+	f := &vm.frames[n-1]
+	if f.name != nil {
+		e.Command = _summarize(_format1(f.name))
+	}
+	e.Args = f.args
+	return f
+}
+
+//called as cmd starts, after its frame is pushed
+func (vm *VM) _trace_invoke() {
+	e := &TraceEvent{Kind: Runtime_trace, VM: vm.id, Time: time.Now(),
+		Message: "invoking"}
+	if f := vm._trace_command(e); f != nil {
+		f.start = e.Time
+	}
+	vm._emit(e)
+}
+
+//called as a command finishes, before its frame is popped
+func (vm *VM) _trace_return(result Word) {
+	e := &TraceEvent{Kind: Runtime_trace, VM: vm.id, Time: time.Now(),
+		Message: "returned", Result: result}
+	if f := vm._trace_command(e); f != nil && !f.start.IsZero() {
+		e.Duration = e.Time.Sub(f.start)
+	}
+	vm._emit(e)
 }
 
-func parse_trace(message ...interface{}) {
-	_tracer(Parser_trace, "P:", message)
+func parse_trace(vm *VM, message ...interface{}) {
+	_tracer(vm, Parser_trace, message)
 }
 
-func sys_trace(message ...interface{}) {
-	_tracer(System_trace, "S:", message)
+func sys_trace(vm *VM, message ...interface{}) {
+	_tracer(vm, System_trace, message)
 }
 
-func run_trace(message ...interface{}) {
-	_tracer(Runtime_trace, "R:", message)
+func run_trace(vm *VM, message ...interface{}) {
+	_tracer(vm, Runtime_trace, message)
 }
 
-func alien_trace(message ...interface{}) {
-	_tracer(Alien_trace, "X:", message)
+func alien_trace(vm *VM, message ...interface{}) {
+	_tracer(vm, Alien_trace, message)
 }
 
 func _serialize_parse_tree(s *sNode) []byte {
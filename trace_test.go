@@ -0,0 +1,143 @@
+package gelo_test
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+
+	"code.google.com/p/gelo"
+)
+
+//keeps a copy of each event it is told of
+type _trace_rec struct {
+	mux    sync.Mutex
+	events []gelo.TraceEvent
+	result []string
+}
+
+func (r *_trace_rec) Trace(e *gelo.TraceEvent) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.events = append(r.events, *e)
+	res := ""
+	if e.Result != nil {
+		res = e.Result.Ser().String()
+	}
+	r.result = append(r.result, res)
+}
+
+//the events with message msg, formatted, and their results
+func (r *_trace_rec) find(msg string) (ev, res []string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for i, e := range r.events {
+		if e.Message == msg {
+			ev = append(ev, e.String())
+			res = append(res, r.result[i])
+		}
+	}
+	return
+}
+
+func _equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTraceEvents(t *testing.T) {
+	r := &_trace_rec{}
+	defer gelo.SetEventTracer(gelo.SetEventTracer(r))
+	gelo.TraceOn(gelo.Runtime_trace)
+	defer gelo.TraceOff(gelo.Runtime_trace)
+	vm := _new_vm()
+	_eval(t, vm, "+ 1 [* 2 3]")
+	if ev, _ := r.find("invoking"); !_equal(ev,
+		[]string{"R: invoking * 2 3", "R: invoking + 1 6"}) {
+		t.Errorf("invoking events %q", ev)
+	}
+	if _, res := r.find("returned"); !_equal(res, []string{"6", "7"}) {
+		t.Errorf("returned %q", res)
+	}
+	for _, e := range r.events {
+		if e.Kind != gelo.Runtime_trace || e.VM != vm.ProcID() ||
+			e.Time.IsZero() {
+			t.Errorf("event %+v", e)
+		}
+	}
+}
+
+func TestFilterTracer(t *testing.T) {
+	r := &_trace_rec{}
+	defer gelo.SetEventTracer(gelo.SetEventTracer(gelo.FilterTracer(r,
+		gelo.TraceFilter{Kinds: gelo.Runtime_trace,
+			Command: regexp.MustCompile(`^\*$`)})))
+	gelo.TraceOn(gelo.Runtime_trace | gelo.System_trace)
+	defer gelo.TraceOff(gelo.Runtime_trace | gelo.System_trace)
+	_eval(t, _new_vm(), "+ 1 [* 2 3]")
+	if len(r.events) == 0 {
+		t.Fatal("the filter passed nothing")
+	}
+	for _, e := range r.events {
+		if e.Kind != gelo.Runtime_trace || e.Command != "*" {
+			t.Errorf("the filter passed %s", e.String())
+		}
+	}
+}
+
+func TestVMTracer(t *testing.T) {
+	global := &_trace_rec{}
+	defer gelo.SetEventTracer(gelo.SetEventTracer(global))
+	r := &_trace_rec{}
+	vm, other := _new_vm(), _new_vm()
+	vm.SetEventTracer(r)
+	vm.TraceOn(gelo.Runtime_trace)
+	_eval(t, other, "+ 1 1")
+	//spawned VMs inherit the tracer
+	_eval(t, vm, "safe-eval { + 2 2 }")
+	if _, res := r.find("returned"); !_equal(res, []string{"4", "4"}) {
+		t.Errorf("returned %q", res)
+	}
+	if len(global.events) != 0 {
+		t.Errorf("the default tracer was told of %d events",
+			len(global.events))
+	}
+	ids := make(map[uint32]bool)
+	for _, e := range r.events {
+		ids[e.VM] = true
+	}
+	if len(ids) != 2 || ids[other.ProcID()] {
+		t.Errorf("events from the VMs %v", ids)
+	}
+	vm.DefaultTracing()
+	n := len(r.events)
+	_eval(t, vm, "+ 3 3")
+	if len(r.events) != n {
+		t.Error("the VM kept its tracer after DefaultTracing")
+	}
+}
+
+//run with -race
+func TestVMTracersConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, vm := &_trace_rec{}, _new_vm()
+			vm.SetEventTracer(r)
+			vm.TraceOn(gelo.Runtime_trace)
+			vm.Do("repeat 10 { + 1 1 }")
+			if _, res := r.find("returned"); len(res) == 0 {
+				t.Error("the tracer of the VM was told of nothing")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -1,5 +1,10 @@
 package gelo
 
+import (
+	"context"
+	"math/big"
+)
+
 type Word interface {
 	Ser() Symbol
 	Copy() Word     //these two should be in a MutableWord interface since
@@ -29,6 +34,15 @@ type Port interface {
 	Closed() bool
 }
 
+//A Port whose blocking operations can be abandoned. If ctx is done before
+//the operation completes, the operation has no effect and ctx.Err() is
+//returned.
+type ContextPort interface {
+	Port
+	SendContext(ctx context.Context, w Word) error
+	RecvContext(ctx context.Context) (Word, error)
+}
+
 type Error interface {
 	Word
 	error
@@ -39,8 +53,16 @@ type Error interface {
 type Bool bool
 
 type Number struct {
-	num float64
+	num float64 //the value, or the nearest float64 to it if exact
 	ser []byte
+	i   *big.Int //the value if it is an exact integer
+	r   *big.Rat //the value if it is an exact fraction, never an integer
+}
+
+type Decimal struct {
+	unscaled *big.Int //the value times 10 to the scale
+	scale    int      //the number of digits after the point
+	mode     RoundingMode
 }
 
 type List struct {
@@ -0,0 +1,374 @@
+package gelo
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Unmarshal fills Go values from words, so a host can read its configuration
+//out of a VM. A word is stored in a Go value according to the value's type:
+//
+//	ints, uints, floats   a number, or a symbol that parses as one
+//	string, []byte        the serialization of any word
+//	bool                  a Bool, or the symbol true or false
+//	time.Duration         a duration such as 1m30s, or a number of nanoseconds
+//	slices, arrays        a list, arrays must have as many items as elements
+//	maps                  a dict, the keys of the map must be strings
+//	structs               a dict, see below
+//	pointers              what the pointer points to, allocated if nil
+//	Words                 a word of that type, lists and dicts are unserialized
+//	interface{}           the natural Go value: float64, string, bool,
+//	                      []interface{} or map[string]interface{}, or the
+//	                      word itself if it is none of these
+//
+//Types that implement encoding.TextUnmarshaler are given the serialization of
+//the word instead. Null stores the zero value in pointers, interfaces, slices
+//and maps.
+//
+//The keys of a dict are matched to the fields of a struct by the names
+//described for NewObject, a gelo tag of "-" hiding a field, and failing an
+//exact match, by a case-insensitive one. Keys that match no field are ignored,
+//as are fields that no key matches.
+
+//An UnmarshalError says which part of a word could not be stored and why
+type UnmarshalError struct {
+	Path string //such as servers[2].port, empty for the word itself
+	Msg  string
+}
+
+func (e *UnmarshalError) Error() string {
+	if e.Path == "" {
+		return "gelo: unmarshal: " + e.Msg
+	}
+	return "gelo: unmarshal " + e.Path + ": " + e.Msg
+}
+
+var _text_unmarshaler_type = reflect.TypeOf(
+	(*encoding.TextUnmarshaler)(nil)).Elem()
+
+//Store w in the value v points to, which must be a non-nil pointer.
+//Returns an *UnmarshalError if part of w cannot be stored in the matching part
+//of v, in which case v may have been partly filled.
+func Unmarshal(w Word, v interface{}) error {
+	return _unmarshal_into("", w, v)
+}
+
+//Unmarshal the value of the variable name into v
+//Call when vm is not running or from its goroutine, such as from an Alien.
+func (vm *VM) ReadInto(name string, v interface{}) error {
+	vm._sanity("read a variable into a Go value")
+	vm.mux.RLock()
+	defer vm.mux.RUnlock()
+	w, ok := vm.cns.copyOut(name)
+	if !ok {
+		return &UnmarshalError{name, "undefined variable"}
+	}
+	return _unmarshal_into(name, w, v)
+}
+
+func _unmarshal_into(path string, w Word, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("gelo: Unmarshal needs a non-nil pointer, not " +
+			fmt.Sprint(reflect.TypeOf(v)))
+	}
+	return _unmarshal(path, w, rv.Elem())
+}
+
+func _unmarshal_fail(path string, exp string, w Word) error {
+	var got string
+	if IsNullString(w) {
+		got = "nothing"
+	} else {
+		got = strconv.Quote(_summarize(w.Ser().Bytes()))
+	}
+	return &UnmarshalError{path, "expected " + exp + ", got " + got}
+}
+
+func _unmarshal(path string, w Word, v reflect.Value) error {
+	t := v.Type()
+	if IsNullString(w) {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+	}
+	if reflect.PtrTo(t).Implements(_text_unmarshaler_type) {
+		u := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := u.UnmarshalText(w.Ser().Bytes()); err != nil {
+			return &UnmarshalError{path, err.Error()}
+		}
+		return nil
+	}
+	if t.Implements(_word_type) || t == _word_type {
+		return _unmarshal_word(path, w, v)
+	}
+	if t == _duration_type {
+		if d, err := time.ParseDuration(w.Ser().String()); err == nil {
+			v.SetInt(int64(d))
+			return nil
+		}
+		if _, ok := NewNumberFrom(w); !ok {
+			return _unmarshal_fail(path, "a duration", w)
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return _unmarshal(path, w, v.Elem())
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return &UnmarshalError{path, "cannot unmarshal into " + t.String()}
+		}
+		v.Set(reflect.ValueOf(_natural(w)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n, ok := NewNumberFrom(w)
+		if !ok {
+			return _unmarshal_fail(path, "an integer", w)
+		}
+		i, ok := n.Int()
+		if !ok && !n.IsInteger() {
+			return _unmarshal_fail(path, "an integer", w)
+		}
+		if !ok || v.OverflowInt(i) {
+			return &UnmarshalError{path, w.Ser().String() +
+				" is out of range for " + t.String()}
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		n, ok := NewNumberFrom(w)
+		if !ok {
+			return _unmarshal_fail(path, "an integer", w)
+		}
+		u, ok, in_range := _uint(n)
+		if !ok {
+			return _unmarshal_fail(path, "an integer", w)
+		}
+		if !in_range || v.OverflowUint(u) {
+			return &UnmarshalError{path, w.Ser().String() +
+				" is out of range for " + t.String()}
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		n, ok := NewNumberFrom(w)
+		if !ok {
+			return _unmarshal_fail(path, "a number", w)
+		}
+		if v.OverflowFloat(n.Real()) {
+			return &UnmarshalError{path, w.Ser().String() +
+				" is out of range for " + t.String()}
+		}
+		v.SetFloat(n.Real())
+	case reflect.String:
+		v.SetString(w.Ser().String())
+	case reflect.Bool:
+		switch b := w.(type) {
+		case Bool:
+			v.SetBool(b.True())
+		default:
+			switch w.Ser().String() {
+			case "true":
+				v.SetBool(true)
+			case "false":
+				v.SetBool(false)
+			default:
+				return _unmarshal_fail(path, "a bool", w)
+			}
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(dup(w.Ser().Bytes()))
+			return nil
+		}
+		l, ok := _unmarshal_list(w)
+		if !ok {
+			return _unmarshal_fail(path, "a list", w)
+		}
+		s := reflect.MakeSlice(t, l.Len(), l.Len())
+		for i := 0; l != nil; l, i = l.Next, i+1 {
+			err := _unmarshal(_path_index(path, i), l.Value, s.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		l, ok := _unmarshal_list(w)
+		if !ok {
+			return _unmarshal_fail(path, "a list", w)
+		}
+		if n := l.Len(); n != t.Len() {
+			return &UnmarshalError{path, fmt.Sprintf(
+				"expected a list of %d items, got %d", t.Len(), n)}
+		}
+		for i := 0; l != nil; l, i = l.Next, i+1 {
+			err := _unmarshal(_path_index(path, i), l.Value, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return &UnmarshalError{path, "cannot unmarshal into " + t.String()}
+		}
+		d, ok := _unmarshal_dict(w)
+		if !ok {
+			return _unmarshal_fail(path, "a dict", w)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(d.rep)))
+		}
+		for k, val := range d.rep {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := _unmarshal(_path_key(path, k), val, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+		}
+	case reflect.Struct:
+		d, ok := _unmarshal_dict(w)
+		if !ok {
+			return _unmarshal_fail(path, "a dict", w)
+		}
+		return _unmarshal_struct(path, d, v)
+	default:
+		return &UnmarshalError{path, "cannot unmarshal into " + t.String()}
+	}
+	return nil
+}
+
+func _path_key(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func _path_index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+func _unmarshal_list(w Word) (*List, bool) {
+	if l, ok := w.(*List); ok {
+		return l, true
+	}
+	return UnserializeListFrom(w)
+}
+
+func _unmarshal_dict(w Word) (*Dict, bool) {
+	if d, ok := w.(*Dict); ok {
+		return d, true
+	}
+	return UnserializeDictFrom(w)
+}
+
+func _unmarshal_struct(path string, d *Dict, v reflect.Value) error {
+	fields := make(map[string]reflect.StructField)
+	folded := make(map[string]reflect.StructField)
+	for _, sf := range reflect.VisibleFields(v.Type()) {
+		name, _, ok := _field_tag(sf)
+		if !ok {
+			continue
+		}
+		fields[name] = sf
+		if _, ok := folded[strings.ToLower(name)]; !ok {
+			folded[strings.ToLower(name)] = sf
+		}
+	}
+	for k, val := range d.rep {
+		sf, ok := fields[k]
+		if !ok {
+			if sf, ok = folded[strings.ToLower(k)]; !ok {
+				continue
+			}
+		}
+		f, err := _field_alloc(v, sf.Index)
+		if err != nil {
+			return &UnmarshalError{_path_key(path, k), err.Error()}
+		}
+		if err := _unmarshal(_path_key(path, k), val, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//the field of v at index, allocating any nil embedded pointers on the way
+func _field_alloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, errors.New("cannot set embedded pointer to " +
+						"unexported struct " + v.Type().Elem().String())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func _unmarshal_word(path string, w Word, v reflect.Value) error {
+	t := v.Type()
+	var out Word
+	ok := true
+	switch t {
+	case reflect.TypeOf((*Number)(nil)):
+		out, ok = NewNumberFrom(w)
+	case reflect.TypeOf((*Decimal)(nil)):
+		out, ok = NewDecimalFrom(w)
+	case reflect.TypeOf((*List)(nil)):
+		out, ok = _unmarshal_list(w)
+	case reflect.TypeOf((*Dict)(nil)):
+		out, ok = _unmarshal_dict(w)
+	case reflect.TypeOf((*Symbol)(nil)).Elem():
+		out = w.Ser()
+	default:
+		out = w
+		ok = reflect.TypeOf(w).AssignableTo(t)
+	}
+	if !ok {
+		return &UnmarshalError{path, "expected " + t.String() + ", got " +
+			w.Type().String()}
+	}
+	v.Set(reflect.ValueOf(out).Convert(t))
+	return nil
+}
+
+//the Go value a word most naturally is
+func _natural(w Word) interface{} {
+	switch t := w.(type) {
+	case *Number:
+		return t.Real()
+	case Bool:
+		return t.True()
+	case Symbol:
+		return t.String()
+	case *List:
+		out := make([]interface{}, 0, t.Len())
+		for ; t != nil; t = t.Next {
+			out = append(out, _natural(t.Value))
+		}
+		return out
+	case *Dict:
+		out := make(map[string]interface{}, len(t.rep))
+		for k, v := range t.rep {
+			out[k] = _natural(v)
+		}
+		return out
+	}
+	return w
+}
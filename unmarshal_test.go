@@ -0,0 +1,117 @@
+package gelo_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"code.google.com/p/gelo"
+)
+
+type _server struct {
+	Host string
+	Port int `gelo:"port"`
+	Addr netip.Addr
+}
+
+type _settings struct {
+	Name    string
+	Debug   bool
+	Timeout time.Duration
+	Ratio   float64
+	Servers []_server
+	Limits  map[string]uint8
+	Pair    [2]int
+	Extra   *_server
+	Any     interface{}
+	Hidden  string `gelo:"-"`
+}
+
+const _settings_src = `set! settings [Dict {
+	{name app}
+	{debug true}
+	{timeout 1m30s}
+	{ratio 1/4}
+	{hidden no}
+	{unknown ignored}
+}]
+set! a [Dict {{host a} {port 80} {addr 10.0.0.1}}]
+set! b [Dict {{host b} {port 81}}]
+dict $settings set! servers [List $a $b]
+dict $settings set! pair [List 1 2]
+dict $settings set! limits [Dict {{files 10} {procs 2}}]
+dict $settings set! extra [Dict {{host c}}]
+dict $settings set! any [List x [Dict {{k v}}]]`
+
+func TestUnmarshal(t *testing.T) {
+	vm := _new_vm()
+	_eval(t, vm, _settings_src)
+	var s _settings
+	if err := vm.ReadInto("settings", &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "app" || !s.Debug || s.Timeout != 90*time.Second ||
+		s.Ratio != 0.25 || s.Pair != [2]int{1, 2} || s.Hidden != "" {
+		t.Errorf("read %+v", s)
+	}
+	if len(s.Servers) != 2 || s.Servers[1].Port != 81 ||
+		s.Servers[0].Addr != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("servers %+v", s.Servers)
+	}
+	if s.Limits["files"] != 10 || s.Limits["procs"] != 2 {
+		t.Errorf("limits %v", s.Limits)
+	}
+	if s.Extra == nil || s.Extra.Host != "c" {
+		t.Errorf("extra %+v", s.Extra)
+	}
+	any, ok := s.Any.([]interface{})
+	if !ok || len(any) != 2 || any[0] != "x" {
+		t.Errorf("any %#v", s.Any)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	vm := _new_vm()
+	//each sets v
+	for _, c := range []struct{ src, path string }{
+		{"set! v [Dict]; dict $v set! servers [List [Dict {{port 80}}] " +
+			"[Dict {{port x}}]]", "v.servers[1].port"},
+		{"set! v [Dict]; dict $v set! limits [Dict {{files 256}}]",
+			"v.limits.files"},
+		{"set! v [Dict {{debug maybe}}]", "v.debug"},
+		{"set! v [Dict]; dict $v set! pair [List 1 2 3]", "v.pair"},
+		{"set! v [Dict {{timeout soon}}]", "v.timeout"},
+		{"set! v [Dict]; dict $v set! servers [List [Dict {{addr nowhere}}]]",
+			"v.servers[0].addr"},
+		{"set! v notadict", "v"},
+		//used to panic rather than fail
+		{`set! v " "`, "v"},
+	} {
+		_eval(t, vm, c.src)
+		var s _settings
+		err := vm.ReadInto("v", &s)
+		var ue *gelo.UnmarshalError
+		if !errors.As(err, &ue) {
+			t.Errorf("%s: expected an UnmarshalError, got %v", c.src, err)
+		} else if ue.Path != c.path || ue.Msg == "" {
+			t.Errorf("%s: failed at %q: %s, want %s", c.src, ue.Path,
+				ue.Msg, c.path)
+		}
+	}
+	var s _settings
+	if err := vm.ReadInto("undefined", &s); err == nil {
+		t.Error("read an undefined variable")
+	}
+	if err := gelo.Unmarshal(gelo.StrToSym("1"), s); err == nil {
+		t.Error("unmarshalled into a struct that is not a pointer")
+	}
+	var l []int
+	if err := gelo.Unmarshal(gelo.StrToSym("{1 2"), &l); err == nil {
+		t.Error("unmarshalled an unclosed list")
+	}
+	var m map[int]int
+	if err := gelo.Unmarshal(gelo.StrToSym("{{1 2}}"), &m); err == nil {
+		t.Error("unmarshalled into a map with int keys")
+	}
+}
@@ -1,6 +1,9 @@
 package gelo
 
-import "bytes"
+import (
+	"bytes"
+	"reflect"
+)
 
 type reader interface {
 	Read([]byte) (int, error)
@@ -128,6 +131,9 @@ func EscapeItem(item []byte) []byte {
 }
 
 func UnescapeItem(item []byte, pos int) ([]byte, int, bool) {
+	if pos >= len(item) {
+		return nil, 0, false
+	}
 	str := false
 	if item[pos] == '"' {
 		str = true
@@ -189,13 +195,23 @@ func SlurpWS(s []byte, pos int) int {
 	panic("SlurpWS in impossible state") //Issue 65
 }
 
+//Converts a Go value to a word. Values of types without a case below are
+//converted by _convert_reflect, see convert.go.
 func Convert(item interface{}) Word {
+	return _convert(item, 0)
+}
+
+func _convert(item interface{}, depth int) Word {
+	if d, ok := item.(*Decimal); ok {
+		//or it would be made a number below
+		return d
+	}
 	var word Word
 	word, ok := NewNumberFromGo(item) //easier to check this first
 	if !ok {
 		switch t := item.(type) {
 		default:
-			programmerError(nil, "Convert given unknown type")
+			word = _convert_reflect(reflect.ValueOf(item), depth)
 		case nil:
 			word = Null
 		case func(*VM, *List, uint) Word:
@@ -231,11 +247,11 @@ func Convert(item interface{}) Word {
 			if len(t) == 0 {
 				word = EmptyList
 			} else {
-				l := &List{Convert(t[0]), nil}
+				l := &List{_convert(t[0], depth+1), nil}
 				word = l
 				if len(t) > 1 {
 					for _, val := range t[1:] {
-						l.Next = &List{Convert(val), nil}
+						l.Next = &List{_convert(val, depth+1), nil}
 						l = l.Next
 					}
 				}
@@ -243,7 +259,7 @@ func Convert(item interface{}) Word {
 		case map[string]interface{}:
 			tmp := make(map[string]Word)
 			for k, v := range t {
-				tmp[k] = Convert(v)
+				tmp[k] = _convert(v, depth+1)
 			}
 			word = &Dict{rep: tmp}
 		case map[string]Word:
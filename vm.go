@@ -1,6 +1,10 @@
 package gelo
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
 
 const VERSION = "0.1.0 alpha"
 
@@ -10,6 +14,13 @@ var _max_id_mutex sync.Mutex
 
 type halt_control_code *List
 type kill_control_code byte
+
+//raised by break and continue, stopped by InvokeLoopBody
+type break_control_code struct{ value Word }
+type continue_control_code struct{}
+
+//raised by return, stopped by the eval of the quote returned from
+type return_control_code struct{ value Word }
 type defert struct{}
 
 type VM struct {
@@ -23,6 +34,33 @@ type VM struct {
 	id          uint32
 	kill_switch chan bool
 	heritage    *_heritage
+	pos         SrcPos //position of the command being evaluated
+	frames      []_frame
+	budget      *_budget
+	own_budget  bool //false if budget is inherited from our parent
+	ctx         context.Context
+	done        <-chan struct{} //ctx.Done(), nil if ctx can never be done
+	quotas      *_quotas
+	stack       []Word //words of the commands being built, see bytecode.go
+	marks       []_mark
+	defers      []_deferred //registered by the quotes being evaluated
+	hook        Hook
+	pause       *_pause
+	prof        *_profiler //nil unless profiling
+	cover       *_coverage //nil unless recording coverage
+	codec       SnapshotCodec
+	trace       atomic.Value //*_trace_cfg, nil to follow the globals
+	modules     *_modules
+	importing   []string //the modules being evaluated, to find cycles
+	loops       int      //loop bodies in this command, see InvokeLoopBody
+}
+
+//Step budgets are shared by a VM and the VMs it spawns, so that a script
+//cannot escape its limit by spawning children. A VM given its own limit still
+//counts against any limit it inherited.
+type _budget struct {
+	limit, used int64
+	up          *_budget
 }
 
 type _heritage struct {
@@ -52,7 +90,9 @@ error:
 // functions to create or destroy virtual machines
 
 func _newVM(io Port) *VM {
-	vm := &VM{io: io, mux: new(sync.RWMutex), kill_switch: make(chan bool)}
+	//buffered so that a kill is never blocked waiting for the VM to notice
+	vm := &VM{io: io, mux: new(sync.RWMutex), kill_switch: make(chan bool, 1),
+		pause: &_pause{}}
 	//proxies
 	vm.API = &api{vm}
 	vm.Ns = &namespace_api{vm}
@@ -67,7 +107,7 @@ func NewVM(io Port) *VM {
 	vm := _newVM(io)
 	vm.cns = newNamespace(nil)
 	vm.cns.set(argument_sym, Null)
-	sys_trace("VM", vm.id, "created")
+	sys_trace(vm, "VM", vm.id, "created")
 	return vm
 }
 
@@ -88,7 +128,15 @@ func (vm *VM) Spawn() *VM {
 	}
 	vm.heritage.children[vm2.id] = vm2.kill_switch
 	vm2.heritage.parent = vm
-	sys_trace("VM", vm2.id, "spawned from VM", vm.id)
+	vm2.budget = vm.budget
+	vm2.quotas = vm.quotas
+	vm2.hook = vm.hook
+	vm2.modules = vm.modules
+	if c := vm._trace_cfg(); c != nil {
+		vm2.trace.Store(c)
+	}
+	vm2._set_context(vm.ctx)
+	sys_trace(vm2, "VM", vm2.id, "spawned from VM", vm.id)
 	return vm2
 }
 
@@ -98,10 +146,10 @@ func (vm *VM) Destroy() {
 	}
 	if vm.running {
 		//therefore we are in a different goroutine
-		vm.kill_switch <- true
+		_send_kill(vm.kill_switch)
 		return
 	}
-	sys_trace("VM", vm.id, "destroyed")
+	sys_trace(vm, "VM", vm.id, "destroyed")
 	//either already dead or never had a parent
 	if vm.heritage != nil {
 		h := vm.heritage
@@ -115,7 +163,7 @@ func (vm *VM) Destroy() {
 		//if we spawned any VMs, kill them
 		if h.children != nil {
 			for _, child := range h.children {
-				child <- true
+				_send_kill(child)
 			}
 			h.children = nil
 		} else {
@@ -150,12 +198,51 @@ func Kill(vm *VM) {
 		//between the test and the send. Sending a kill to a destroyed VM
 		//is safe.
 		if kill_switch := vm.kill_switch; kill_switch != nil {
-			sys_trace("VM", vm.id, "sent kill signal")
-			kill_switch <- true
+			sys_trace(vm, "VM", vm.id, "sent kill signal")
+			_send_kill(kill_switch)
 		}
 	}
 }
 
+//never blocks, if there's already a kill pending there's no need for another
+func _send_kill(kill_switch chan bool) {
+	select {
+	case kill_switch <- true:
+	default:
+	}
+}
+
+//returns the previous context so that it can be restored
+func (vm *VM) _set_context(ctx context.Context) context.Context {
+	old := vm.ctx
+	vm.ctx, vm.done = ctx, nil
+	if ctx != nil {
+		vm.done = ctx.Done()
+	}
+	return old
+}
+
+//called between commands, to see if anyone wants us to stop
+func (vm *VM) _poll() {
+	select {
+	case <-vm.kill_switch:
+		panic(kill_control_code(byte(0)))
+	case <-vm.done:
+		cancelled(vm, vm.ctx.Err())
+	default:
+	}
+}
+
+//The context of the Exec or Do vm is running or, if vm is idle, the context
+//it inherited from its parent. Returns context.Background() if there is
+//neither. Blocking Aliens should give up when it is done.
+func (vm *VM) Context() context.Context {
+	if vm.ctx == nil {
+		return context.Background()
+	}
+	return vm.ctx
+}
+
 func (vm *VM) IsDead() bool {
 	return vm == nil || vm.API == nil
 }
@@ -181,6 +268,38 @@ func (vm *VM) Redirect(io Port) Port {
 	return p
 }
 
+//Limit the number of commands vm may evaluate during a single Exec or Do,
+//including any evaluated by the VMs it spawns, after which a runtime error of
+//kind ErrKindStepLimit is raised. n <= 0 removes a limit set on vm, but any
+//limit vm inherited from its parent still applies.
+//It is not safe to call this while the vm is running.
+func (vm *VM) SetStepLimit(n int64) {
+	vm._sanity("set a step limit")
+	up := vm.budget
+	if vm.own_budget {
+		up = vm.budget.up
+	}
+	if n <= 0 {
+		vm.budget, vm.own_budget = up, false
+		return
+	}
+	vm.budget, vm.own_budget = &_budget{limit: n, up: up}, true
+}
+
+func (vm *VM) _reset_budget() {
+	if vm.own_budget {
+		atomic.StoreInt64(&vm.budget.used, 0)
+	}
+}
+
+func (vm *VM) _step() {
+	for b := vm.budget; b != nil; b = b.up {
+		if atomic.AddInt64(&b.used, 1) > b.limit {
+			stepLimitExceeded(vm, b.limit)
+		}
+	}
+}
+
 // commands to get information from a virtual machine
 
 func (vm *VM) ProcID() uint32 {
@@ -197,6 +316,16 @@ func (vm *VM) Register(name string, item interface{}) {
 	vm.API.Trace("Registered:", name)
 }
 
+//Register the Go function fn as an Alien made by NewAlienFromFunc
+func (vm *VM) RegisterFunc(name string, fn interface{}) {
+	vm.Register(name, NewAlienFromFunc(name, fn))
+}
+
+//Register an object made by NewObject for ptr, a pointer to a struct
+func (vm *VM) RegisterObject(name string, ptr interface{}) {
+	vm.Register(name, NewObject(name, ptr))
+}
+
 func (vm *VM) RegisterBundle(bundle map[string]interface{}) {
 	for name, item := range bundle {
 		vm.Register(name, item)
@@ -275,6 +404,9 @@ func (vm *VM) ReadMap(name string) (map[string]Word, bool) {
 		return nil, false
 	}
 	m, ok := M.(*Dict)
+	if !ok {
+		return nil, false
+	}
 	return map[string]Word(m.rep), true //no point in copying twice
 }
 
@@ -410,11 +542,22 @@ func (vm *VM) GetProgram() Quote {
 	return &protected_quote{vm.program}
 }
 
+//name is the name of the source being read, such as a file name, and is
+//used to report the positions of errors. It may be "".
 //Never call from a goroutine that doesn't own the VM
-func (vm *VM) ParseProgram(in reader) (err Error) {
+func (vm *VM) ParseProgram(name string, in reader) (err Error) {
 	vm._sanity("parse and set a new program")
 	vm.mux.Lock()
 	defer vm.mux.Unlock()
+	sys_trace(vm, "parsing")
+	program, err := _parse_source(name, in)
+	if err == nil {
+		vm.program = program
+	}
+	return
+}
+
+func _parse_source(name string, in reader) (q *quote, err Error) {
 	defer func() {
 		if x := recover(); x != nil {
 			if synerr, ok := x.(*ErrSyntax); ok {
@@ -424,30 +567,37 @@ func (vm *VM) ParseProgram(in reader) (err Error) {
 			panic(x)
 		}
 	}()
-	sys_trace("parsing")
 	reader := newRecordingReader(in)
-	code := parse(reader)
-	vm.program = &quote{false, code, reader.Bytes()}
-	return
+	start := SrcPos{name, 1, 1}
+	code := parse(reader, start)
+	return &quote{false, code, reader.Bytes(), start, nil}, nil
 }
 
 //call from a different goroutine than the vm's and the outcome is undefined,
 //if the VM is executing a program
 func (vm *VM) Do(in string) (ret Word, err Error) {
+	return vm.DoContext(vm.Context(), in)
+}
+
+//Like Do but the evaluation of in is abandoned with a runtime error of kind
+//ErrKindCancelled if ctx is done before it completes. Any VMs spawned by in
+//inherit ctx.
+func (vm *VM) DoContext(ctx context.Context, in string) (ret Word, err Error) {
 	vm._sanity("execute: " + in)
 	vm.mux.Lock()
 	defer vm.mux.Unlock()
+	defer vm._set_context(vm._set_context(ctx))
 	defer func() {
 		if x := recover(); x != nil {
 			switch t := x.(type) {
 			default:
 				panic(x)
 			case kill_control_code:
-				sys_trace("VM", vm.id, "was killed")
+				sys_trace(vm, "VM", vm.id, "was killed")
 				vm.Destroy()
 				ret, err = nil, killed(vm)
 			case halt_control_code:
-				sys_trace("VM", vm.id, "halted")
+				sys_trace(vm, "VM", vm.id, "halted")
 				ret = (*List)(t)
 			case *ErrRuntime:
 				//Syntax error would be in the source file
@@ -456,7 +606,13 @@ func (vm *VM) Do(in string) (ret Word, err Error) {
 			}
 		}
 	}()
-	code := parse(newBufFromString(in))
+	var code *_code
+	if cmds := parse(newBufFromString(in), SrcPos{"", 1, 1}); cmds != nil {
+		code = compile(cmds)
+		if vm.cover != nil {
+			vm.cover._commands(cmds, &_cover_src{})
+		}
+	}
 	//we do this so a syntax error raised by the program can be caught but
 	//a syntax error in 'in' is reported
 	defer func() {
@@ -468,43 +624,57 @@ func (vm *VM) Do(in string) (ret Word, err Error) {
 			}
 		}
 		vm.running = false
+		vm._unwind(_depth{})
 	}()
 	vm.running = true
-	ret = vm.eval(code, nil).DeepCopy()
+	vm._reset_budget()
+	vm.pos = SrcPos{"", 1, 1}
+	vm._push_frame(NewList(interns("<do>")))
+	ret = vm.eval(code, nil, true).DeepCopy()
 	vm.cns.set(argument_sym, Null)
 	return
 }
 
 //Never call from a goroutine that doesn't own the VM
 func (vm *VM) Exec(args interface{}) (ret Word, err Error) {
+	return vm.ExecContext(vm.Context(), args)
+}
+
+//Like Exec but the program is abandoned with a runtime error of kind
+//ErrKindCancelled if ctx is done before it completes. Any VMs spawned by the
+//program inherit ctx.
+//Never call from a goroutine that doesn't own the VM
+func (vm *VM) ExecContext(ctx context.Context, args interface{}) (ret Word, err Error) {
 	vm._sanity("execute its program")
 	vm.mux.Lock()
 	defer vm.mux.Unlock()
+	defer vm._set_context(vm._set_context(ctx))
 	if vm.program == nil {
 		programmerError(vm, "attempted to execute VM with no program")
 	}
 	defer func() {
 		vm.running = false //true even if we error out before setting this true
+		vm._unwind(_depth{})
 		if x := recover(); x != nil {
 			switch t := x.(type) {
 			default:
 				//either a _errSystem or bad programming
 				//regardless, the system is now in a bad state so panic
-				sys_trace("UNABLE TO RECOVER FROM PANIC")
+				sys_trace(vm, "UNABLE TO RECOVER FROM PANIC")
 				panic(x)
 			case kill_control_code:
-				sys_trace("VM", vm.id, "killed")
+				sys_trace(vm, "VM", vm.id, "killed")
 				vm.Destroy()
 				ret, err = nil, killed(vm)
 			case halt_control_code:
-				sys_trace("VM", vm.id, "halted")
+				sys_trace(vm, "VM", vm.id, "halted")
 				ret = (*List)(t)
 			case Error:
 				//there was a reasonable error, return it
 				ret, err = nil, x.(Error)
 			}
 		} else {
-			sys_trace("Program halted without error")
+			sys_trace(vm, "Program halted without error")
 		}
 	}()
 
@@ -513,23 +683,29 @@ func (vm *VM) Exec(args interface{}) (ret Word, err Error) {
 		Args = AsList(Convert(args))
 	}
 
-	sys_trace("evaluating with arguments", Args)
-	code, ok := vm.program.fcode()
+	sys_trace(vm, "evaluating with arguments", Args)
+	code, ok := vm.program.fbytecode()
 	if !ok {
 		//Somehow the program's quote was altered since it has been set
 		systemError(vm, "The program has become corrupt")
 	}
+	if vm.cover != nil {
+		vm.cover._quote(vm.program)
+	}
 
 	vm.running = true //unset in defer handler
-	ret = vm.eval(code, Args).DeepCopy()
+	vm._reset_budget()
+	vm.pos = vm.program.pos
+	vm._push_frame(&List{interns("<program>"), Args})
+	ret = vm.eval(code, Args, true).DeepCopy()
 	vm.cns.set(argument_sym, Null)
 	return
 }
 
 //same as ParseProgram followed by Exec
 //Never call from a goroutine that doesn't own the VM
-func (vm *VM) Run(in reader, args interface{}) (ret Word, err Error) {
-	if err = vm.ParseProgram(in); err != nil {
+func (vm *VM) Run(name string, in reader, args interface{}) (ret Word, err Error) {
+	if err = vm.ParseProgram(name, in); err != nil {
 		return
 	}
 	return vm.Exec(args)
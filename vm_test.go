@@ -0,0 +1,43 @@
+package gelo_test
+
+import (
+	"testing"
+
+	"code.google.com/p/gelo"
+	"code.google.com/p/gelo/commands"
+)
+
+//a VM with the core and all of the commands registered
+func _new_vm() *gelo.VM {
+	vm := gelo.NewVM(gelo.NewChan())
+	vm.RegisterBundle(gelo.Core)
+	vm.RegisterBundles(commands.All)
+	return vm
+}
+
+//evaluates src in vm and returns the serialization of the result, failing t if
+//there is an error
+func _eval(t *testing.T, vm *gelo.VM, src string) string {
+	t.Helper()
+	ret, err := vm.Do(src)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", src, err)
+	}
+	return ret.Ser().String()
+}
+
+//evaluates src in vm and returns the error it raises, failing t if of a kind
+//other than kind or if there is none
+func _eval_err(t *testing.T, vm *gelo.VM, src, kind string) gelo.Error {
+	t.Helper()
+	ret, err := vm.Do(src)
+	if err == nil {
+		t.Fatalf("%s: expected an error of kind %s, got %s", src, kind,
+			ret.Ser())
+	}
+	if k := gelo.ErrorKind(err); k != kind {
+		t.Fatalf("%s: expected an error of kind %s, got %s: %v", src, kind, k,
+			err)
+	}
+	return err
+}